@@ -9,7 +9,8 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-func newClient(configPath string) (*kubernetes.Clientset, error) {
+// newClient creates a Kubernetes client whose requests are rate-limited to qps, allowing bursts of up to burst requests.
+func newClient(configPath string, qps float32, burst int) (*kubernetes.Clientset, error) {
 	var config *rest.Config
 
 	// Check if the kubeConfig file exists.
@@ -31,5 +32,8 @@ func newClient(configPath string) (*kubernetes.Clientset, error) {
 		config.Host = masterURI
 	}
 
+	config.QPS = qps
+	config.Burst = burst
+
 	return kubernetes.NewForConfig(config)
 }
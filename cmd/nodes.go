@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/vkubelet"
+)
+
+// simulatedNode is one of the nodes registered by the process, with its own provider instance and ports.
+type simulatedNode struct {
+	name        string
+	provider    providers.Provider
+	apiConfig   vkubelet.APIConfig
+	metricsAddr string
+}
+
+// nodeNames returns the names of the count nodes registered by the process. A single node is named after
+// the node name flag, and several nodes are named after it followed by their index.
+func nodeNames(name string, count int) []string {
+	if count == 1 {
+		return []string{name}
+	}
+
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", name, i)
+	}
+	return names
+}
+
+// offsetAddr returns addr with its port increased by offset, so that each node listens on its own port.
+func offsetAddr(addr string, offset int) (string, error) {
+	if offset == 0 {
+		return addr, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing address %s", addr)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return "", errors.Wrapf(err, "error parsing the port of address %s", addr)
+	}
+	if n == 0 {
+		// Each node already listens on a port picked by the system.
+		return addr, nil
+	}
+	return net.JoinHostPort(host, strconv.Itoa(n+offset)), nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNodeNames(t *testing.T) {
+	for _, c := range []struct {
+		count    int
+		expected []string
+	}{
+		{1, []string{"vk"}},
+		{3, []string{"vk-0", "vk-1", "vk-2"}},
+	} {
+		if names := nodeNames("vk", c.count); !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("Got %v for %d nodes, expected %v", names, c.count, c.expected)
+		}
+	}
+}
+
+func TestOffsetAddr(t *testing.T) {
+	for _, c := range []struct {
+		addr     string
+		offset   int
+		expected string
+	}{
+		{":10250", 0, ":10250"},
+		{":10250", 2, ":10252"},
+		{"127.0.0.1:10255", 1, "127.0.0.1:10256"},
+		{"[::1]:10255", 1, "[::1]:10256"},
+		{"127.0.0.1:0", 1, "127.0.0.1:0"},
+	} {
+		addr, err := offsetAddr(c.addr, c.offset)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", c.addr, err)
+			continue
+		}
+		if addr != c.expected {
+			t.Errorf("Got %s for %s offset by %d, expected %s", addr, c.addr, c.offset, c.expected)
+		}
+	}
+
+	for _, addr := range []string{"10250", ":http"} {
+		if _, err := offsetAddr(addr, 1); err == nil {
+			t.Errorf("Expected an error for %s", addr)
+		}
+	}
+}
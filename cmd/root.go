@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/cpuguy83/strongerrors"
@@ -38,6 +39,7 @@ import (
 	"go.opencensus.io/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 const (
@@ -48,6 +50,7 @@ var kubeletConfig string
 var kubeConfig string
 var kubeNamespace string
 var nodeName string
+var nodeCount int
 var operatingSystem string
 var provider string
 var providerConfig string
@@ -56,11 +59,17 @@ var disableTaint bool
 var logLevel string
 var metricsAddr string
 var taint *corev1.Taint
-var k8sClient *kubernetes.Clientset
-var p providers.Provider
+var k8sClient kubernetes.Interface
+var standalonePods string
+var deterministicFast bool
 var rm *manager.ResourceManager
-var apiConfig vkubelet.APIConfig
+var nodes []simulatedNode
 var podSyncWorkers int
+var kubeAPIQPS float32
+var kubeAPIBurst int
+var nodeLeaseDurationSeconds int32
+var nodeStatusUpdateInterval time.Duration
+var enableProfiling bool
 
 var userTraceExporters []string
 var userTraceConfig = TracingExporterOptions{Tags: make(map[string]string)}
@@ -76,31 +85,55 @@ This allows users to schedule kubernetes workloads on nodes that aren't running
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx, cancel := context.WithCancel(context.Background())
 
-		f, err := vkubelet.New(ctx, vkubelet.Config{
-			Client:          k8sClient,
-			Namespace:       kubeNamespace,
-			NodeName:        nodeName,
-			Taint:           taint,
-			MetricsAddr:     metricsAddr,
-			Provider:        p,
-			ResourceManager: rm,
-			APIConfig:       apiConfig,
-			PodSyncWorkers:  podSyncWorkers,
-		})
-		if err != nil {
-			log.L.WithError(err).Fatal("Error initializing virtual kubelet")
+		servers := make([]*vkubelet.Server, len(nodes))
+		for i, n := range nodes {
+			ctx := ctx
+			if len(nodes) > 1 {
+				ctx = log.WithLogger(ctx, log.L.WithField("node", n.name))
+			}
+
+			f, err := vkubelet.New(ctx, vkubelet.Config{
+				Client:          k8sClient,
+				Namespace:       kubeNamespace,
+				NodeName:        n.name,
+				Taint:           taint,
+				MetricsAddr:     n.metricsAddr,
+				Provider:        n.provider,
+				ResourceManager: rm,
+				APIConfig:       n.apiConfig,
+				PodSyncWorkers:  podSyncWorkers,
+
+				NodeLeaseDurationSeconds: nodeLeaseDurationSeconds,
+				NodeStatusUpdateInterval: nodeStatusUpdateInterval,
+				EnableProfiling:          enableProfiling,
+			})
+			if err != nil {
+				log.G(ctx).WithError(err).Fatal("Error initializing virtual kubelet")
+			}
+			servers[i] = f
 		}
 
 		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 		go func() {
 			<-sig
-			f.Stop()
+			for _, f := range servers {
+				f.Stop()
+			}
+			rm.Stop()
 			cancel()
 		}()
 
-		if err := f.Run(ctx); err != nil && errors.Cause(err) != context.Canceled {
-			log.L.Fatal(err)
+		errs := make(chan error, len(servers))
+		for _, f := range servers {
+			go func(f *vkubelet.Server) {
+				errs <- f.Run(ctx)
+			}(f)
+		}
+		for range servers {
+			if err := <-errs; err != nil && errors.Cause(err) != context.Canceled {
+				log.L.Fatal(err)
+			}
 		}
 	},
 }
@@ -160,7 +193,8 @@ func init() {
 	//RootCmd.PersistentFlags().StringVar(&kubeletConfig, "config", "", "config file (default is $HOME/.virtual-kubelet.yaml)")
 	RootCmd.PersistentFlags().StringVar(&kubeConfig, "kubeconfig", "", "config file (default is $HOME/.kube/config)")
 	RootCmd.PersistentFlags().StringVar(&kubeNamespace, "namespace", "", "kubernetes namespace (default is 'all')")
-	RootCmd.PersistentFlags().StringVar(&nodeName, "nodename", defaultNodeName, "kubernetes node name")
+	RootCmd.PersistentFlags().StringVar(&nodeName, "nodename", defaultNodeName, "kubernetes node name, or the prefix of the node names with --node-count")
+	RootCmd.PersistentFlags().IntVar(&nodeCount, "node-count", 1, "number of nodes to register, named after --nodename followed by their index, each with its own provider instance, kubelet port and metrics port")
 	RootCmd.PersistentFlags().StringVar(&operatingSystem, "os", "Linux", "Operating System (Linux/Windows)")
 	RootCmd.PersistentFlags().StringVar(&provider, "provider", "", "cloud provider")
 	RootCmd.PersistentFlags().BoolVar(&disableTaint, "disable-taint", false, "disable the virtual-kubelet node taint")
@@ -171,6 +205,13 @@ func init() {
 	RootCmd.PersistentFlags().MarkDeprecated("taint", "Taint key should now be configured using the VK_TAINT_KEY environment variable")
 	RootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", `set the log level, e.g. "trace", debug", "info", "warn", "error"`)
 	RootCmd.PersistentFlags().IntVar(&podSyncWorkers, "pod-sync-workers", 1, `set the number of pod synchronization workers`)
+	RootCmd.PersistentFlags().Float32Var(&kubeAPIQPS, "kube-api-qps", rest.DefaultQPS, "QPS to use while talking with the kubernetes API server")
+	RootCmd.PersistentFlags().IntVar(&kubeAPIBurst, "kube-api-burst", rest.DefaultBurst, "burst to allow while talking with the kubernetes API server")
+	RootCmd.PersistentFlags().DurationVar(&nodeStatusUpdateInterval, "node-status-update-interval", 5*time.Second, "interval between updates of the node status and its condition heartbeats")
+	RootCmd.PersistentFlags().StringVar(&standalonePods, "standalone-pods", "", "run without an API server, against an in-process fake one holding the pods of this YAML or JSON manifest file")
+	RootCmd.PersistentFlags().BoolVar(&deterministicFast, "deterministic-fast", false, "turn off the chaos, injected latency and errors, startup delays and rate limiting of simulation providers, keeping their workload model")
+	RootCmd.PersistentFlags().BoolVar(&enableProfiling, "enable-profiling", false, "serve the runtime profiles of the process on /debug/pprof/ of the metrics server")
+	RootCmd.PersistentFlags().Int32Var(&nodeLeaseDurationSeconds, "node-lease-duration-seconds", 0, "duration of the node lease, renewed every quarter of it (0 disables the node lease)")
 
 	RootCmd.PersistentFlags().StringSliceVar(&userTraceExporters, "trace-exporter", nil, fmt.Sprintf("sets the tracing exporter to use, available exporters: %s", AvailableTraceExporters()))
 	RootCmd.PersistentFlags().StringVar(&userTraceConfig.ServiceName, "trace-service-name", "virtual-kubelet", "sets the name of the service used to register with the trace exporter")
@@ -247,14 +288,30 @@ func initConfig() {
 		}
 	}
 
-	k8sClient, err = newClient(kubeConfig)
-	if err != nil {
-		logger.WithError(err).Fatal("Error creating kubernetes client")
+	if nodeStatusUpdateInterval <= 0 {
+		logger.Fatal("The node status update interval should be positive")
 	}
 
-	rm, err = manager.NewResourceManager(k8sClient)
+	if nodeLeaseDurationSeconds < 0 {
+		logger.Fatal("The node lease duration should not be negative")
+	}
+
+	if kubeAPIQPS <= 0 || kubeAPIBurst <= 0 {
+		logger.Fatal("The kubernetes API QPS and burst should be positive")
+	}
+
+	if nodeCount <= 0 {
+		logger.Fatal("The number of nodes should be positive")
+	}
+	names := nodeNames(nodeName, nodeCount)
+
+	if standalonePods != "" {
+		k8sClient, err = newStandaloneClient(standalonePods, names[0])
+	} else {
+		k8sClient, err = newClient(kubeConfig, kubeAPIQPS, kubeAPIBurst)
+	}
 	if err != nil {
-		logger.WithError(err).Fatal("Error initializing resource manager")
+		logger.WithError(err).Fatal("Error creating kubernetes client")
 	}
 
 	daemonPortEnv := getEnv("KUBELET_PORT", defaultDaemonPort)
@@ -263,23 +320,58 @@ func initConfig() {
 		logger.WithError(err).WithField("value", daemonPortEnv).Fatal("Invalid value from KUBELET_PORT in environment")
 	}
 
-	initConfig := register.InitConfig{
-		ConfigPath:      providerConfig,
-		NodeName:        nodeName,
-		OperatingSystem: operatingSystem,
-		ResourceManager: rm,
-		DaemonPort:      int32(daemonPort),
-		InternalIP:      os.Getenv("VKUBELET_POD_IP"),
+	apiConfig, err := getAPIConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("Error reading API config")
 	}
 
-	p, err = register.GetProvider(provider, initConfig)
+	// The nodes share the resource manager, whose pod cache holds the pods of every node, and each node has its
+	// own provider instance and ports, following the ones of the first node.
+	rm, err = manager.NewResourceManager(k8sClient)
 	if err != nil {
-		logger.WithError(err).Fatal("Error initializing provider")
+		logger.WithError(err).Fatal("Error initializing resource manager")
 	}
 
-	apiConfig, err = getAPIConfig()
-	if err != nil {
-		logger.WithError(err).Fatal("Error reading API config")
+	nodes = make([]simulatedNode, len(names))
+	for i, name := range names {
+		nodeLogger := logger.WithField("node", name)
+
+		initConfig := register.InitConfig{
+			ConfigPath:      providerConfig,
+			NodeName:        name,
+			OperatingSystem: operatingSystem,
+			ResourceManager: rm,
+			DaemonPort:      int32(daemonPort) + int32(i),
+			InternalIP:      os.Getenv("VKUBELET_POD_IP"),
+
+			DeterministicFast: deterministicFast,
+		}
+
+		p, err := register.GetProvider(provider, initConfig)
+		if err != nil {
+			nodeLogger.WithError(err).Fatal("Error initializing provider")
+		}
+
+		nodeAPIConfig := apiConfig
+		nodeAPIConfig.Addr, err = offsetAddr(apiConfig.Addr, i)
+		if err != nil {
+			nodeLogger.WithError(err).Fatal("Error reading API config")
+		}
+
+		nodeMetricsAddr := metricsAddr
+		if metricsAddr != "" {
+			nodeMetricsAddr, err = offsetAddr(metricsAddr, i)
+			if err != nil {
+				nodeLogger.WithError(err).Fatal("Invalid metrics address")
+			}
+		}
+
+		nodes[i] = simulatedNode{
+			name:        name,
+			provider:    p,
+			apiConfig:   nodeAPIConfig,
+			metricsAddr: nodeMetricsAddr,
+		}
 	}
 
 	if podSyncWorkers <= 0 {
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newStandaloneClient creates an in-process fake Kubernetes client instead of connecting to an API server.
+// It holds the pods of the manifest file at podsPath, so that virtual-kubelet runs them without a control
+// plane, e.g. for quick experiments and benchmarks. The pods which have no node name are bound to the node.
+func newStandaloneClient(podsPath, nodeName string) (kubernetes.Interface, error) {
+	pods, err := readPods(podsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]runtime.Object, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Namespace == "" {
+			pod.Namespace = corev1.NamespaceDefault
+		}
+		if pod.UID == "" {
+			pod.UID = uuid.NewUUID()
+		}
+		if pod.CreationTimestamp.IsZero() {
+			pod.CreationTimestamp = metav1.Now()
+		}
+		if pod.Spec.NodeName == "" {
+			pod.Spec.NodeName = nodeName
+		}
+		objects = append(objects, pod)
+	}
+
+	return fake.NewSimpleClientset(objects...), nil
+}
+
+// readPods reads the pods of a file holding a stream of YAML or JSON pod manifests.
+func readPods(path string) ([]*corev1.Pod, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening pod manifests")
+	}
+	defer f.Close()
+
+	var pods []*corev1.Pod
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		pod := &corev1.Pod{}
+		if err := decoder.Decode(pod); err != nil {
+			if err == io.EOF {
+				return pods, nil
+			}
+			return nil, errors.Wrapf(err, "error decoding pod manifest %d", len(pods)+1)
+		}
+		if pod.Name == "" {
+			return nil, errors.Errorf("pod manifest %d has no name", len(pods)+1)
+		}
+		pods = append(pods, pod)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func writePods(t *testing.T, data string) string {
+	f, err := ioutil.TempFile("", "pods")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestNewStandaloneClient(t *testing.T) {
+	path := writePods(t, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  containers:
+  - name: c
+    image: busybox
+---
+{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "bar", "namespace": "kube-system"}, "spec": {"containers": [{"name": "c", "image": "busybox"}]}}
+---
+{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "baz"}, "spec": {"nodeName": "vk-1", "containers": [{"name": "c", "image": "busybox"}]}}
+`)
+	defer os.Remove(path)
+
+	client, err := newStandaloneClient(path, "vk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods, err := client.CoreV1().Pods(corev1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 3 {
+		t.Fatalf("Got %d pods, expected 3", len(pods.Items))
+	}
+	for _, pod := range pods.Items {
+		expected := "vk"
+		if pod.Name == "baz" {
+			expected = "vk-1"
+		}
+		if pod.Spec.NodeName != expected {
+			t.Errorf("Got node name %q for %s, expected %s", pod.Spec.NodeName, pod.Name, expected)
+		}
+		if pod.UID == "" {
+			t.Errorf("Expected %s to have a UID", pod.Name)
+		}
+	}
+	if _, err := client.CoreV1().Pods(corev1.NamespaceDefault).Get("foo", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected foo in the default namespace: %v", err)
+	}
+}
+
+func TestReadPodsInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"kind": "Pod", "metadata": {}}`,
+		`{"kind": "Pod", "metadata": {"name": 1}}`,
+	} {
+		path := writePods(t, data)
+		defer os.Remove(path)
+		if _, err := readPods(path); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+
+	if _, err := readPods("/nonexistent"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
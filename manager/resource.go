@@ -23,6 +23,11 @@ type ResourceManager struct {
 	configMaps   map[string]*v1.ConfigMap
 	secretRef    map[string]int64
 	secrets      map[string]*v1.Secret
+
+	// stop is closed by Stop to stop the ConfigMap and Secret watches and the cache cleanup.
+	stop     chan struct{}
+	stopOnce sync.Once
+	watches  []watch.Interface
 }
 
 // NewResourceManager returns a ResourceManager with the internal maps initialized.
@@ -35,6 +40,7 @@ func NewResourceManager(k8sClient kubernetes.Interface) (*ResourceManager, error
 		configMaps:   make(map[string]*v1.ConfigMap, 0),
 		secrets:      make(map[string]*v1.Secret, 0),
 		k8sClient:    k8sClient,
+		stop:         make(chan struct{}),
 	}
 
 	configW, err := rm.k8sClient.CoreV1().ConfigMaps(v1.NamespaceAll).Watch(metav1.ListOptions{})
@@ -44,15 +50,24 @@ func NewResourceManager(k8sClient kubernetes.Interface) (*ResourceManager, error
 
 	secretsW, err := rm.k8sClient.CoreV1().Secrets(v1.NamespaceAll).Watch(metav1.ListOptions{})
 	if err != nil {
+		configW.Stop()
 		return nil, errors.Wrap(err, "error getting secrets watch")
 	}
+	rm.watches = []watch.Interface{configW, secretsW}
 
 	go rm.watchConfigMaps(configW)
 	go rm.watchSecrets(secretsW)
 
-	tick := time.Tick(5 * time.Minute)
+	ticker := time.NewTicker(5 * time.Minute)
 	go func() {
-		for range tick {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-rm.stop:
+				return
+			}
+
 			rm.Lock()
 			for n, c := range rm.secretRef {
 				if c <= 0 {
@@ -81,6 +96,17 @@ func NewResourceManager(k8sClient kubernetes.Interface) (*ResourceManager, error
 	return &rm, nil
 }
 
+// Stop stops the ConfigMap and Secret watches and the cleanup of their cache.
+// The cached ConfigMaps and Secrets are no longer evicted once they change.
+func (rm *ResourceManager) Stop() {
+	rm.stopOnce.Do(func() {
+		close(rm.stop)
+		for _, w := range rm.watches {
+			w.Stop()
+		}
+	})
+}
+
 // SetPods clears the internal cache and populates it with the supplied pods.
 func (rm *ResourceManager) SetPods(pods *v1.PodList) {
 	rm.Lock()
@@ -154,6 +180,28 @@ func (rm *ResourceManager) DeletePod(p *v1.Pod) bool {
 	return false
 }
 
+// ReplacePod replaces the cached pod old with updated, which is an updated copy of it. It reports whether old
+// was replaced, which is not the case if the cached pod changed meanwhile.
+// Cached pods are shared, so they must be copied rather than modified in place.
+func (rm *ResourceManager) ReplacePod(old, updated *v1.Pod) bool {
+	rm.Lock()
+	defer rm.Unlock()
+
+	podKey := rm.getStoreKey(old.Namespace, old.Name)
+	if p, ok := rm.pods[podKey]; ok && p == old {
+		rm.decrementRefCounters(old)
+		rm.pods[podKey] = updated
+		rm.incrementRefCounters(updated)
+		return true
+	}
+	if p, ok := rm.deletingPods[podKey]; ok && p == old {
+		rm.deletingPods[podKey] = updated
+		return true
+	}
+
+	return false
+}
+
 // GetPod retrieves the specified pod from the cache. It returns nil if a pod is not found.
 func (rm *ResourceManager) GetPod(namespace, name string) *v1.Pod {
 	rm.RLock()
@@ -271,39 +319,39 @@ func (rm *ResourceManager) watchSecrets(w watch.Interface) {
 }
 
 func (rm *ResourceManager) incrementRefCounters(p *v1.Pod) {
-	for _, c := range p.Spec.Containers {
+	rm.updateRefCounters(p, 1)
+}
+
+func (rm *ResourceManager) decrementRefCounters(p *v1.Pod) {
+	rm.updateRefCounters(p, -1)
+}
+
+// updateRefCounters adds delta to the reference counters of the ConfigMaps and Secrets the pod references
+// from the environment of its containers and init containers, and from its volumes.
+func (rm *ResourceManager) updateRefCounters(p *v1.Pod, delta int64) {
+	containers := append(append([]v1.Container(nil), p.Spec.InitContainers...), p.Spec.Containers...)
+	for _, c := range containers {
 		for _, e := range c.Env {
 			if e.ValueFrom != nil && e.ValueFrom.ConfigMapKeyRef != nil {
 				configMapKey := rm.getStoreKey(p.Namespace, e.ValueFrom.ConfigMapKeyRef.Name)
-				rm.configMapRef[configMapKey]++
+				rm.configMapRef[configMapKey] += delta
 			}
 
 			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
 				secretKey := rm.getStoreKey(p.Namespace, e.ValueFrom.SecretKeyRef.Name)
-				rm.secretRef[secretKey]++
+				rm.secretRef[secretKey] += delta
 			}
 		}
-	}
 
-	for _, v := range p.Spec.Volumes {
-		if v.VolumeSource.Secret != nil {
-			secretKey := rm.getStoreKey(p.Namespace, v.VolumeSource.Secret.SecretName)
-			rm.secretRef[secretKey]++
-		}
-	}
-}
-
-func (rm *ResourceManager) decrementRefCounters(p *v1.Pod) {
-	for _, c := range p.Spec.Containers {
-		for _, e := range c.Env {
-			if e.ValueFrom != nil && e.ValueFrom.ConfigMapKeyRef != nil {
-				configMapKey := rm.getStoreKey(p.Namespace, e.ValueFrom.ConfigMapKeyRef.Name)
-				rm.configMapRef[configMapKey]--
+		for _, e := range c.EnvFrom {
+			if e.ConfigMapRef != nil {
+				configMapKey := rm.getStoreKey(p.Namespace, e.ConfigMapRef.Name)
+				rm.configMapRef[configMapKey] += delta
 			}
 
-			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
-				secretKey := rm.getStoreKey(p.Namespace, e.ValueFrom.SecretKeyRef.Name)
-				rm.secretRef[secretKey]--
+			if e.SecretRef != nil {
+				secretKey := rm.getStoreKey(p.Namespace, e.SecretRef.Name)
+				rm.secretRef[secretKey] += delta
 			}
 		}
 	}
@@ -311,7 +359,7 @@ func (rm *ResourceManager) decrementRefCounters(p *v1.Pod) {
 	for _, v := range p.Spec.Volumes {
 		if v.VolumeSource.Secret != nil {
 			secretKey := rm.getStoreKey(p.Namespace, v.VolumeSource.Secret.SecretName)
-			rm.secretRef[secretKey]--
+			rm.secretRef[secretKey] += delta
 		}
 	}
 }
@@ -66,6 +66,31 @@ func makePod(namespace, name string) *v1.Pod {
 	return pod
 }
 
+func TestResourceManagerReplacePod(t *testing.T) {
+	pm, err := NewResourceManager(fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1 := makePod("Pod1Namespace", "Pod1")
+	pm.UpdatePod(pod1)
+
+	updated := pod1.DeepCopy()
+	updated.Status.Phase = v1.PodRunning
+	if !pm.ReplacePod(pod1, updated) {
+		t.Error("Expected the pod to be replaced")
+	}
+	if got := pm.GetPod(pod1.Namespace, pod1.Name); got != updated {
+		t.Errorf("Got %v, wanted the updated pod", got)
+	}
+	if pod1.Status.Phase != "" {
+		t.Errorf("Got phase %s, expected the replaced pod to be left unchanged", pod1.Status.Phase)
+	}
+
+	if pm.ReplacePod(pod1, pod1.DeepCopy()) {
+		t.Error("Expected a stale pod not to be replaced")
+	}
+}
+
 func TestResourceManagerUpdatePod(t *testing.T) {
 	pm, err := NewResourceManager(fakeClient)
 	if err != nil {
@@ -100,3 +125,56 @@ func TestResourceManagerUpdatePod(t *testing.T) {
 		t.Errorf("Got %s, wanted %s", gotPod1.Namespace, pod1.Namespace)
 	}
 }
+
+func TestResourceManagerStop(t *testing.T) {
+	rm, err := NewResourceManager(fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rm.Stop()
+	rm.Stop()
+
+	for _, w := range rm.watches {
+		if _, ok := <-w.ResultChan(); ok {
+			t.Error("Expected the watch to be stopped")
+		}
+	}
+}
+
+func TestResourceManagerRefCounters(t *testing.T) {
+	pm, err := NewResourceManager(fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Stop()
+
+	pod := makePod("ns", "pod")
+	pod.Spec.InitContainers = []v1.Container{{
+		Name:    "init",
+		EnvFrom: []v1.EnvFromSource{{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}}},
+	}}
+	pod.Spec.Containers = []v1.Container{{
+		Name: "c",
+		Env: []v1.EnvVar{{Name: "VAR", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: "config"}, Key: "key",
+		}}}},
+		EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "secret"}}}},
+	}}
+
+	pm.UpdatePod(pod)
+	if n := pm.configMapRef[pm.getStoreKey("ns", "config")]; n != 2 {
+		t.Errorf("Got %d references to the ConfigMap, expected 2", n)
+	}
+	if n := pm.secretRef[pm.getStoreKey("ns", "secret")]; n != 1 {
+		t.Errorf("Got %d references to the Secret, expected 1", n)
+	}
+
+	pm.DeletePod(pod)
+	if n := pm.configMapRef[pm.getStoreKey("ns", "config")]; n != 0 {
+		t.Errorf("Got %d references to the ConfigMap once the pod is deleted, expected 0", n)
+	}
+	if n := pm.secretRef[pm.getStoreKey("ns", "secret")]; n != 0 {
+		t.Errorf("Got %d references to the Secret once the pod is deleted, expected 0", n)
+	}
+}
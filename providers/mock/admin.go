@@ -0,0 +1,282 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Names of the provider operations, as used by the admin API, trace files and audit files.
+const (
+	operationCreatePod    = "CreatePod"
+	operationUpdatePod    = "UpdatePod"
+	operationDeletePod    = "DeletePod"
+	operationGetPodStatus = "GetPodStatus"
+
+	// Operations which are only audited.
+	operationGetPod           = "GetPod"
+	operationGetPods          = "GetPods"
+	operationGetContainerLogs = "GetContainerLogs"
+	operationExecInContainer  = "ExecInContainer"
+)
+
+var failableOperations = map[string]bool{
+	operationCreatePod:    true,
+	operationUpdatePod:    true,
+	operationDeletePod:    true,
+	operationGetPodStatus: true,
+}
+
+// nodeCapacity is the capacity of the node set through the admin API.
+type nodeCapacity struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+	Pods   string `json:"pods,omitempty"`
+}
+
+// conditionOverride replaces the status of a node condition reported by the provider.
+type conditionOverride struct {
+	Status  v1.ConditionStatus `json:"status"`
+	Reason  string             `json:"reason,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+// injectedFailure makes the next Count calls of a provider operation fail.
+type injectedFailure struct {
+	Count   int    `json:"count"`
+	Message string `json:"message,omitempty"`
+}
+
+// nodeLease controls the renewal of the node lease.
+type nodeLease struct {
+	Paused bool `json:"paused"`
+}
+
+// podTermination describes how a pod is terminated through the admin API.
+type podTermination struct {
+	ExitCode int32  `json:"exitCode,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// startAdminServer starts the admin HTTP server, which mutates the state of the provider
+// while it is running.
+func (p *MockProvider) startAdminServer(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error setting up admin listener: %v", err)
+	}
+
+	p.adminServer = &http.Server{Handler: p.adminHandler()}
+	p.adminDone = make(chan struct{})
+	go func() {
+		defer close(p.adminDone)
+		if err := p.adminServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			p.logger.Printf("admin server stopped: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// adminHandler creates an http handler for mutating and inspecting the state of the provider.
+func (p *MockProvider) adminHandler() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/capacity", p.handleCapacity).Methods("PUT")
+	r.HandleFunc("/conditions/{type}", p.handleCondition).Methods("PUT")
+	r.HandleFunc("/pods/{namespace}/{name}/terminate", p.handleTerminatePod).Methods("POST")
+	r.HandleFunc("/failures/{operation}", p.handleFailure).Methods("PUT")
+	r.HandleFunc("/lease", p.handleLease).Methods("PUT")
+	r.HandleFunc("/namespaces", p.handleNamespaces).Methods("GET")
+	r.HandleFunc("/cpus", p.handleCPUs).Methods("GET")
+	return r
+}
+
+// handleCapacity replaces the capacity of the node. Resources which are not set are left unchanged.
+func (p *MockProvider) handleCapacity(w http.ResponseWriter, req *http.Request) {
+	var capacity nodeCapacity
+	if err := json.NewDecoder(req.Body).Decode(&capacity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, q := range []string{capacity.CPU, capacity.Memory, capacity.Pods} {
+		if q == "" {
+			continue
+		}
+		if quantity, err := resource.ParseQuantity(q); err != nil || quantity.Sign() < 0 {
+			http.Error(w, fmt.Sprintf("invalid quantity %q", q), http.StatusBadRequest)
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if capacity.CPU != "" {
+		p.config.CPU = capacity.CPU
+	}
+	if capacity.Memory != "" {
+		p.config.Memory = capacity.Memory
+	}
+	if capacity.Pods != "" {
+		p.config.Pods = capacity.Pods
+	}
+}
+
+// handleCondition overrides the status of a node condition. Only the conditions reported by NodeConditions
+// can be overridden.
+func (p *MockProvider) handleCondition(w http.ResponseWriter, req *http.Request) {
+	conditionType := v1.NodeConditionType(mux.Vars(req)["type"])
+	if !nodeConditionTypes[conditionType] {
+		http.Error(w, fmt.Sprintf("unknown condition %q", conditionType), http.StatusNotFound)
+		return
+	}
+
+	var o conditionOverride
+	if err := json.NewDecoder(req.Body).Decode(&o); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch o.Status {
+	case v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown:
+	default:
+		http.Error(w, fmt.Sprintf("invalid condition status %q", o.Status), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.conditions[conditionType] = o
+}
+
+// handleTerminatePod forcibly terminates a pod, which is then reported as succeeded if its exit code is 0,
+// and as failed otherwise.
+func (p *MockProvider) handleTerminatePod(w http.ResponseWriter, req *http.Request) {
+	t := podTermination{
+		ExitCode: 137,
+		Reason:   "Killed",
+	}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	vars := mux.Vars(req)
+	key, err := buildKeyFromNames(vars["namespace"], vars["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	pod, ok := p.pods[key]
+	if !ok {
+		p.mu.Unlock()
+		http.Error(w, "pod not found", http.StatusNotFound)
+		return
+	}
+	terminated := newTermination(p.startTimes[key], t.ExitCode, t.Reason, t.Message, metav1.NewTime(p.clock.Now()))
+	p.terminated[key] = terminated
+	p.releasePod(key)
+	p.schedulePrune(key)
+	podIP := p.podIPs[key]
+	p.mu.Unlock()
+
+	p.notifyPodStatus(pod, terminatedPodStatus(pod, terminated, podIP))
+}
+
+// handleFailure makes the next calls of a provider operation fail.
+func (p *MockProvider) handleFailure(w http.ResponseWriter, req *http.Request) {
+	op := mux.Vars(req)["operation"]
+	if !failableOperations[op] {
+		http.Error(w, fmt.Sprintf("unknown operation %q", op), http.StatusNotFound)
+		return
+	}
+
+	var f injectedFailure
+	if err := json.NewDecoder(req.Body).Decode(&f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f.Count <= 0 {
+		delete(p.failures, op)
+		return
+	}
+	p.failures[op] = f
+}
+
+// handleLease pauses or resumes the renewal of the node lease, to simulate a loss of the node heartbeats.
+func (p *MockProvider) handleLease(w http.ResponseWriter, req *http.Request) {
+	var l nodeLease
+	if err := json.NewDecoder(req.Body).Decode(&l); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.leasePaused = l.Paused
+}
+
+// checkInjectedFailure returns an error if a failure of the operation has been injected.
+func (p *MockProvider) checkInjectedFailure(op string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, ok := p.failures[op]
+	if !ok {
+		return nil
+	}
+
+	f.Count--
+	if f.Count <= 0 {
+		delete(p.failures, op)
+	} else {
+		p.failures[op] = f
+	}
+
+	if f.Message == "" {
+		return fmt.Errorf("injected failure of %s", op)
+	}
+	return fmt.Errorf("injected failure of %s: %s", op, f.Message)
+}
+
+// handleNamespaces reports the resources held by the pods of each namespace.
+func (p *MockProvider) handleNamespaces(w http.ResponseWriter, req *http.Request) {
+	p.mu.RLock()
+	usage := p.usageByNamespace()
+	p.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		p.logger.Printf("error writing namespace usage: %v\n", err)
+	}
+}
+
+// handleCPUs reports the cores assigned exclusively to pods and those left in the shared pool.
+func (p *MockProvider) handleCPUs(w http.ResponseWriter, req *http.Request) {
+	p.mu.RLock()
+	pools := p.cpuPools()
+	p.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pools); err != nil {
+		p.logger.Printf("error writing cpu pools: %v\n", err)
+	}
+}
@@ -0,0 +1,241 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestProvider(t *testing.T) *MockProvider {
+	path := writeConfig(t, `{"defaults": {}}`)
+	defer os.Remove(path)
+
+	p, err := NewMockProvider(path, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func makePod(namespace, name string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "c", Image: "busybox"}},
+		},
+	}
+}
+
+func doAdminRequest(t *testing.T, p *MockProvider, method, path, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	p.adminHandler().ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAdminCapacity(t *testing.T) {
+	p := newTestProvider(t)
+
+	if code := doAdminRequest(t, p, "PUT", "/capacity", `{"cpu": "3"}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	capacity := p.Capacity(context.Background())
+	if cpu := capacity[v1.ResourceCPU]; cpu.String() != "3" {
+		t.Errorf("Got cpu %s, expected 3", cpu.String())
+	}
+	if pods := capacity[v1.ResourcePods]; pods.String() != defaultPodCapacity {
+		t.Errorf("Got pods %s, expected %s", pods.String(), defaultPodCapacity)
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/capacity", `{"memory": "lots"}`); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, expected %d", code, http.StatusBadRequest)
+	}
+	if code := doAdminRequest(t, p, "PUT", "/capacity", `{"pods": "-1"}`); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, expected %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminCondition(t *testing.T) {
+	p := newTestProvider(t)
+
+	if code := doAdminRequest(t, p, "PUT", "/conditions/Ready", `{"status": "False", "reason": "Broken"}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	for _, c := range p.NodeConditions(context.Background()) {
+		if c.Type != v1.NodeReady {
+			continue
+		}
+		if c.Status != v1.ConditionFalse || c.Reason != "Broken" {
+			t.Errorf("Got condition %s (%s), expected False (Broken)", c.Status, c.Reason)
+		}
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/conditions/Flaky", `{"status": "True"}`); code != http.StatusNotFound {
+		t.Errorf("Got status %d, expected %d", code, http.StatusNotFound)
+	}
+}
+
+func TestNodeConditionTransitionTime(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	readyTransition := func() metav1.Time {
+		for _, c := range p.NodeConditions(ctx) {
+			if c.Type == v1.NodeReady {
+				return c.LastTransitionTime
+			}
+		}
+		t.Fatal("No Ready condition")
+		return metav1.Time{}
+	}
+
+	first := readyTransition()
+	time.Sleep(10 * time.Millisecond)
+	if second := readyTransition(); !second.Equal(&first) {
+		t.Errorf("Got transition time %v, expected it to stay %v", second, first)
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/conditions/Ready", `{"status": "False"}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if third := readyTransition(); !third.After(first.Time) {
+		t.Errorf("Got transition time %v, expected it to be after %v", third, first)
+	}
+}
+
+func TestAdminTerminatePod(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	if code := doAdminRequest(t, p, "POST", "/pods/default/foo/terminate", ""); code != http.StatusNotFound {
+		t.Errorf("Got status %d, expected %d", code, http.StatusNotFound)
+	}
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if code := doAdminRequest(t, p, "POST", "/pods/default/foo/terminate", `{"exitCode": 1, "reason": "Error"}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodFailed {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodFailed)
+	}
+	if terminated := status.ContainerStatuses[0].State.Terminated; terminated == nil || terminated.ExitCode != 1 {
+		t.Errorf("Got container state %v, expected to be terminated with exit code 1", status.ContainerStatuses[0].State)
+	}
+
+	if err := p.CreatePod(ctx, makePod("default", "bar")); err != nil {
+		t.Fatal(err)
+	}
+	if code := doAdminRequest(t, p, "POST", "/pods/default/bar/terminate", `{"exitCode": 0, "reason": "Completed"}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	if status, err = p.GetPodStatus(ctx, "default", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodSucceeded {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodSucceeded)
+	}
+}
+
+func TestAdminFailure(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	if code := doAdminRequest(t, p, "PUT", "/failures/CreatePod", `{"count": 2}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.CreatePod(ctx, makePod("default", "foo")); err == nil {
+			t.Error("Expected an injected failure")
+		}
+	}
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Error(err)
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/failures/Unknown", `{"count": 1}`); code != http.StatusNotFound {
+		t.Errorf("Got status %d, expected %d", code, http.StatusNotFound)
+	}
+}
+
+func TestAdminLease(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	if !p.ShouldRenewNodeLease(ctx) {
+		t.Error("Expected the node lease to be renewed")
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/lease", `{"paused": true}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	if p.ShouldRenewNodeLease(ctx) {
+		t.Error("Expected the node lease renewal to be paused")
+	}
+
+	if code := doAdminRequest(t, p, "PUT", "/lease", `{"paused": false}`); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+	if !p.ShouldRenewNodeLease(ctx) {
+		t.Error("Expected the node lease to be renewed")
+	}
+}
+
+func TestAdminNamespaces(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	for _, pod := range []*v1.Pod{
+		makePod("default", "foo"),
+		makePod("default", "bar"),
+		makePod("kube-system", "baz"),
+	} {
+		pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m")}
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code := doAdminRequest(t, p, "POST", "/pods/default/bar/terminate", ""); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+
+	req := httptest.NewRequest("GET", "/namespaces", nil)
+	w := httptest.NewRecorder()
+	p.adminHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", w.Code, http.StatusOK)
+	}
+
+	var usage map[string]namespaceUsage
+	if err := json.NewDecoder(w.Body).Decode(&usage); err != nil {
+		t.Fatal(err)
+	}
+	for ns, expected := range map[string]string{"default": "250m", "kube-system": "250m"} {
+		u := usage[ns]
+		if u.Pods != 1 {
+			t.Errorf("Got %d pods in %s, expected 1", u.Pods, ns)
+		}
+		if cpu := u.Requests[v1.ResourceCPU]; cpu.String() != expected {
+			t.Errorf("Got cpu requests %s in %s, expected %s", cpu.String(), ns, expected)
+		}
+	}
+}
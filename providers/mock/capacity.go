@@ -0,0 +1,166 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// systemCriticalPriority is the lowest priority of system critical pods, whose priority classes are
+	// system-cluster-critical and system-node-critical.
+	systemCriticalPriority = 2 * 1000000000
+
+	systemClusterCritical = "system-cluster-critical"
+	systemNodeCritical    = "system-node-critical"
+
+	// preemptedMessage is the message of the status of the pods preempted to make room for critical pods,
+	// which is the one reported by the kubelet.
+	preemptedMessage = "Preempted in order to admit critical pod"
+)
+
+// capacity returns the capacity of the node. p.mu must be held.
+func (p *MockProvider) capacity() v1.ResourceList {
+	capacity := v1.ResourceList{
+		"cpu":    resource.MustParse(p.config.CPU),
+		"memory": resource.MustParse(p.config.Memory),
+		"pods":   resource.MustParse(p.config.Pods),
+	}
+	for size, value := range p.config.HugePages {
+		capacity[v1.ResourceName(v1.ResourceHugePagesPrefix+size)] = resource.MustParse(value)
+	}
+	return capacity
+}
+
+// checkCapacity returns the rejected status of the pod if its requests exceed the capacity of the node left
+// by the other pods holding resources, or nil. It is only checked if EnforceCapacity is set.
+// Critical pods are admitted regardless, and other pods are preempted to make room for them once they are
+// admitted, unless critical pod admission is disabled. p.mu must be held.
+func (p *MockProvider) checkCapacity(pod *v1.Pod) *v1.PodStatus {
+	if !isEnabled(p.config.EnforceCapacity) || p.admitsCriticalPod(pod) {
+		return nil
+	}
+
+	if name, r, u, c, ok := p.insufficientResource(pod); ok {
+		return rejectedPodStatus(providers.PodReasonOutOfPrefix+string(name), fmt.Sprintf("Node didn't have enough resource: %s, requested: %s, used: %s, capacity: %s", name, r.String(), u.String(), c.String()))
+	}
+	return nil
+}
+
+// admitsCriticalPod returns whether the pod is admitted regardless of the capacity left, as a critical pod.
+func (p *MockProvider) admitsCriticalPod(pod *v1.Pod) bool {
+	return isCriticalPod(pod) && !isEnabled(p.config.DisableCriticalPodAdmission)
+}
+
+// insufficientResource returns the first resource, by name, which the node does not have enough of left for
+// the pod by the other pods holding resources, along with the request of the pod, the amount used and the
+// capacity of the node. It returns false if the pod fits. p.mu must be held.
+func (p *MockProvider) insufficientResource(pod *v1.Pod) (v1.ResourceName, resource.Quantity, resource.Quantity, resource.Quantity, bool) {
+	requested := newNamespaceUsage()
+	requested.add(pod, p.resourceDefaults, p.podOverhead(pod))
+	used := newNamespaceUsage()
+	for _, u := range p.usage {
+		used.merge(u, 1)
+	}
+
+	requests := requested.Requests
+	requests[v1.ResourcePods] = *resource.NewQuantity(int64(requested.Pods), resource.DecimalSI)
+	usedRequests := used.Requests
+	usedRequests[v1.ResourcePods] = *resource.NewQuantity(int64(used.Pods), resource.DecimalSI)
+
+	capacity := p.capacity()
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		name := v1.ResourceName(n)
+		r := requests[name]
+		c, ok := capacity[name]
+		if !ok || r.IsZero() {
+			continue
+		}
+		u := usedRequests[name]
+		total := u.DeepCopy()
+		total.Add(r)
+		if total.Cmp(c) > 0 {
+			return name, r, u, c, true
+		}
+	}
+	return "", resource.Quantity{}, resource.Quantity{}, resource.Quantity{}, false
+}
+
+// preemptForCriticalPod terminates pods holding resources, lowest priority first, until the node has enough
+// of them left for the critical pod being admitted, as the kubelet does when the node is full. Critical pods
+// are never preempted, nor are pods which do not request the resource the node lacks, so the critical pod may
+// still be admitted beyond the capacity. p.mu must be held.
+func (p *MockProvider) preemptForCriticalPod(pod *v1.Pod) {
+	if !isEnabled(p.config.EnforceCapacity) || !p.admitsCriticalPod(pod) {
+		return
+	}
+
+	for {
+		name, _, _, _, ok := p.insufficientResource(pod)
+		if !ok {
+			return
+		}
+		victim := p.preemptionVictim(name)
+		if victim == "" {
+			return
+		}
+
+		p.logger.Printf("preempt pod %q to admit critical pod %q\n", p.pods[victim].Name, pod.Name)
+		p.terminated[victim] = newTermination(p.startTimes[victim], 137, providers.PodReasonPreempting, preemptedMessage, metav1.NewTime(p.clock.Now()))
+		p.releasePod(victim)
+		p.schedulePrune(victim)
+	}
+}
+
+// preemptionVictim returns the key of the pod to preempt to free some of the resource: the pod of lowest
+// priority among the non critical pods holding resources which request it, or "" if there is none. Pods of
+// equal priority are ordered by key. p.mu must be held.
+func (p *MockProvider) preemptionVictim(name v1.ResourceName) string {
+	var victim string
+	var victimPriority int32
+	for key, pod := range p.pods {
+		if !p.holdsResources(key) || isCriticalPod(pod) {
+			continue
+		}
+		if name != v1.ResourcePods {
+			u := newNamespaceUsage()
+			u.add(pod, p.resourceDefaults, p.podOverhead(pod))
+			if r := u.Requests[name]; r.IsZero() {
+				continue
+			}
+		}
+
+		var priority int32
+		if pod.Spec.Priority != nil {
+			priority = *pod.Spec.Priority
+		}
+		if victim == "" || priority < victimPriority || (priority == victimPriority && key < victim) {
+			victim, victimPriority = key, priority
+		}
+	}
+	return victim
+}
+
+// isCriticalPod returns whether the pod is admitted even when the node is full: DaemonSet pods, which must run
+// on every node, and system critical pods.
+func isCriticalPod(pod *v1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	if pod.Spec.PriorityClassName == systemClusterCritical || pod.Spec.PriorityClassName == systemNodeCritical {
+		return true
+	}
+	return pod.Spec.Priority != nil && *pod.Spec.Priority >= systemCriticalPriority
+}
@@ -0,0 +1,110 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEnforceCapacity(t *testing.T) {
+	ctx := context.Background()
+
+	makeCPUPod := func(name, cpu string) *v1.Pod {
+		pod := makePod("default", name)
+		pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}
+		return pod
+	}
+	daemon := makeCPUPod("daemon", "1")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "monitoring"}}
+	makePriorityPod := func(pod *v1.Pod, priority int32) *v1.Pod {
+		pod.Spec.Priority = &priority
+		return pod
+	}
+	critical := makeCPUPod("critical", "1")
+	critical.Spec.PriorityClassName = systemNodeCritical
+
+	for _, c := range []struct {
+		name     string
+		config   MockConfig
+		pods     []*v1.Pod
+		expected []string
+		// preempted are the reasons of the pods once they are all created.
+		preempted []string
+	}{
+		{
+			name:     "capacity not enforced",
+			config:   MockConfig{CPU: "2", Pods: "2"},
+			pods:     []*v1.Pod{makeCPUPod("a", "2"), makeCPUPod("b", "1"), makeCPUPod("c", "1")},
+			expected: []string{"", "", ""},
+		},
+		{
+			name:     "out of cpu",
+			config:   MockConfig{CPU: "2", EnforceCapacity: boolPtr(true)},
+			pods:     []*v1.Pod{makeCPUPod("a", "1500m"), makeCPUPod("b", "1"), makeCPUPod("c", "500m")},
+			expected: []string{"", "OutOfcpu", ""},
+		},
+		{
+			name:     "out of pods",
+			config:   MockConfig{Pods: "1", EnforceCapacity: boolPtr(true)},
+			pods:     []*v1.Pod{makeCPUPod("a", "1"), makeCPUPod("b", "1")},
+			expected: []string{"", "OutOfpods"},
+		},
+		{
+			name:      "critical pods admitted",
+			config:    MockConfig{CPU: "2", EnforceCapacity: boolPtr(true)},
+			pods:      []*v1.Pod{makeCPUPod("a", "2"), daemon, critical, makeCPUPod("b", "1")},
+			expected:  []string{"", "", "", "OutOfcpu"},
+			preempted: []string{providers.PodReasonPreempting, "", "", "OutOfcpu"},
+		},
+		{
+			name:   "lowest priority pod preempted",
+			config: MockConfig{CPU: "2", EnforceCapacity: boolPtr(true)},
+			pods: []*v1.Pod{
+				makePriorityPod(makeCPUPod("high", "1"), 100),
+				makePriorityPod(makeCPUPod("low", "1"), 10),
+				critical,
+			},
+			expected:  []string{"", "", ""},
+			preempted: []string{"", providers.PodReasonPreempting, ""},
+		},
+		{
+			name:     "critical pod admission disabled",
+			config:   MockConfig{CPU: "2", EnforceCapacity: boolPtr(true), DisableCriticalPodAdmission: boolPtr(true)},
+			pods:     []*v1.Pod{makeCPUPod("a", "2"), daemon, critical},
+			expected: []string{"", "OutOfcpu", "OutOfcpu"},
+		},
+	} {
+		p, err := NewMockProviderWithConfig(c.config, "vk", "Linux", "10.0.0.1", 10250)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, pod := range c.pods {
+			if err := p.CreatePod(ctx, pod); err != nil {
+				t.Fatal(err)
+			}
+			status, err := p.GetPodStatus(ctx, pod.Namespace, pod.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Reason != c.expected[i] {
+				t.Errorf("%s: Got reason %q for %s, expected %q", c.name, status.Reason, pod.Name, c.expected[i])
+			}
+		}
+		for i, pod := range c.pods {
+			if c.preempted == nil {
+				break
+			}
+			status, err := p.GetPodStatus(ctx, pod.Namespace, pod.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Reason != c.preempted[i] {
+				t.Errorf("%s: Got reason %q for %s once every pod is created, expected %q", c.name, status.Reason, pod.Name, c.preempted[i])
+			}
+		}
+	}
+}
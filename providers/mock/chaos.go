@@ -0,0 +1,224 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// errNodeUnreachable is returned by provider operations while the node is blackholed.
+var errNodeUnreachable = errors.New("node is unreachable")
+
+// ChaosConfig configures the faults randomly injected by the mock provider.
+// Probabilities are in the range [0, 1] and durations are parsed with time.ParseDuration.
+type ChaosConfig struct {
+	// Seed is the seed of the random number generator, so that runs can be reproduced.
+	Seed int64 `json:"seed,omitempty"`
+
+	// KillProbability is the probability of a running pod being killed each time its status is retrieved.
+	KillProbability float64 `json:"killProbability,omitempty"`
+
+	// DelayProbability is the probability of a provider operation being delayed by up to MaxDelay.
+	DelayProbability float64 `json:"delayProbability,omitempty"`
+	MaxDelay         string  `json:"maxDelay,omitempty"`
+
+	// CorruptStatusProbability is the probability of a pod status being reported with an Unknown phase.
+	CorruptStatusProbability float64 `json:"corruptStatusProbability,omitempty"`
+
+	// BlackholeProbability is the probability, each time the node conditions are retrieved, of the node
+	// becoming unreachable for BlackholeDuration.
+	BlackholeProbability float64 `json:"blackholeProbability,omitempty"`
+	BlackholeDuration    string  `json:"blackholeDuration,omitempty"`
+
+	// Operations configures the faults injected into CreatePod, UpdatePod, DeletePod and GetPodStatus,
+	// keyed by operation name.
+	Operations map[string]OperationFaultConfig `json:"operations,omitempty"`
+}
+
+// OperationFaultConfig configures the faults injected into a provider operation.
+type OperationFaultConfig struct {
+	// Latency is added to every call of the operation.
+	Latency string `json:"latency,omitempty"`
+
+	// ErrorRate is the probability of a call failing with an error.
+	ErrorRate float64 `json:"errorRate,omitempty"`
+
+	// DeadlineExceededRate is the probability of a call failing with context.DeadlineExceeded,
+	// as if the provider had timed out.
+	DeadlineExceededRate float64 `json:"deadlineExceededRate,omitempty"`
+}
+
+// operationFault is the parsed form of an OperationFaultConfig.
+type operationFault struct {
+	latency              time.Duration
+	errorRate            float64
+	deadlineExceededRate float64
+}
+
+// chaos injects the faults configured by a ChaosConfig.
+// A nil *chaos never injects any fault.
+type chaos struct {
+	mu                sync.Mutex
+	config            ChaosConfig
+	rand              *rand.Rand
+	maxDelay          time.Duration
+	blackholeDuration time.Duration
+	blackholeUntil    time.Time
+	operations        map[string]operationFault
+	clock             Clock
+}
+
+func newChaos(config *ChaosConfig, clock Clock) (*chaos, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	probabilities := map[string]float64{
+		"killProbability":          config.KillProbability,
+		"delayProbability":         config.DelayProbability,
+		"corruptStatusProbability": config.CorruptStatusProbability,
+		"blackholeProbability":     config.BlackholeProbability,
+	}
+	for name, p := range probabilities {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("Invalid chaos %s %v", name, p)
+		}
+	}
+
+	c := &chaos{
+		config: *config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		clock:  clock,
+	}
+
+	var err error
+	if config.MaxDelay != "" {
+		if c.maxDelay, err = time.ParseDuration(config.MaxDelay); err != nil {
+			return nil, fmt.Errorf("Invalid chaos maxDelay %v", config.MaxDelay)
+		}
+	}
+	if config.BlackholeDuration != "" {
+		if c.blackholeDuration, err = time.ParseDuration(config.BlackholeDuration); err != nil {
+			return nil, fmt.Errorf("Invalid chaos blackholeDuration %v", config.BlackholeDuration)
+		}
+	}
+
+	c.operations = make(map[string]operationFault, len(config.Operations))
+	for op, f := range config.Operations {
+		if !failableOperations[op] {
+			return nil, fmt.Errorf("Invalid chaos operation %v", op)
+		}
+		if f.ErrorRate < 0 || f.ErrorRate > 1 {
+			return nil, fmt.Errorf("Invalid chaos %s errorRate %v", op, f.ErrorRate)
+		}
+		if f.DeadlineExceededRate < 0 || f.DeadlineExceededRate > 1 {
+			return nil, fmt.Errorf("Invalid chaos %s deadlineExceededRate %v", op, f.DeadlineExceededRate)
+		}
+
+		fault := operationFault{errorRate: f.ErrorRate, deadlineExceededRate: f.DeadlineExceededRate}
+		if f.Latency != "" {
+			if fault.latency, err = time.ParseDuration(f.Latency); err != nil || fault.latency < 0 {
+				return nil, fmt.Errorf("Invalid chaos %s latency %v", op, f.Latency)
+			}
+		}
+		c.operations[op] = fault
+	}
+
+	return c, nil
+}
+
+// happens reports whether an event of the given probability happens.
+func (c *chaos) happens(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rand.Float64() < p
+}
+
+// disturb delays the operation and fails it if the node is blackholed or the faults configured
+// for the operation say so.
+func (c *chaos) disturb(ctx context.Context, op string) error {
+	if c == nil {
+		return nil
+	}
+
+	fault := c.operations[op]
+	delay := fault.latency
+	if c.maxDelay > 0 && c.happens(c.config.DelayProbability) {
+		c.mu.Lock()
+		delay += time.Duration(c.rand.Int63n(int64(c.maxDelay)))
+		c.mu.Unlock()
+	}
+	if delay > 0 {
+		select {
+		case <-c.clock.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if c.blackholed() {
+		return errNodeUnreachable
+	}
+	if c.happens(fault.deadlineExceededRate) {
+		return context.DeadlineExceeded
+	}
+	if c.happens(fault.errorRate) {
+		return fmt.Errorf("injected failure of %s", op)
+	}
+	return nil
+}
+
+// killPod reports whether a running pod should be killed.
+func (c *chaos) killPod() bool {
+	if c == nil {
+		return false
+	}
+	return c.happens(c.config.KillProbability)
+}
+
+// corruptStatus reports whether a pod status should be corrupted.
+func (c *chaos) corruptStatus() bool {
+	if c == nil {
+		return false
+	}
+	return c.happens(c.config.CorruptStatusProbability)
+}
+
+// maybeBlackhole randomly makes the node unreachable, and reports whether it is unreachable.
+func (c *chaos) maybeBlackhole() bool {
+	if c == nil {
+		return false
+	}
+	if c.blackholed() {
+		return true
+	}
+	if c.blackholeDuration <= 0 || !c.happens(c.config.BlackholeProbability) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.blackholeUntil = c.clock.Now().Add(c.blackholeDuration)
+	return true
+}
+
+// blackholed reports whether the node is unreachable.
+func (c *chaos) blackholed() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.clock.Now().Before(c.blackholeUntil)
+}
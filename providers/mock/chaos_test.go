@@ -0,0 +1,171 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestChaosKillPod(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	var err error
+	if p.chaos, err = newChaos(&ChaosConfig{KillProbability: 1}, realClock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodFailed {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodFailed)
+	}
+}
+
+func TestChaosBlackhole(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	var err error
+	if p.chaos, err = newChaos(&ChaosConfig{BlackholeProbability: 1, BlackholeDuration: "1h"}, realClock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	conditions := p.NodeConditions(ctx)
+	if conditions[0].Type != v1.NodeReady || conditions[0].Status != v1.ConditionUnknown {
+		t.Errorf("Got condition %s=%s, expected Ready=Unknown", conditions[0].Type, conditions[0].Status)
+	}
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != errNodeUnreachable {
+		t.Errorf("Got error %v, expected %v", err, errNodeUnreachable)
+	}
+}
+
+func TestChaosSeed(t *testing.T) {
+	config := &ChaosConfig{Seed: 42, KillProbability: 0.5}
+	c1, err := newChaos(config, realClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := newChaos(config, realClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if c1.killPod() != c2.killPod() {
+			t.Fatal("Expected the same faults to be injected with the same seed")
+		}
+	}
+}
+
+func TestChaosInvalidConfig(t *testing.T) {
+	if _, err := newChaos(&ChaosConfig{KillProbability: 2}, realClock{}); err == nil {
+		t.Error("Expected an error for an invalid probability")
+	}
+	if _, err := newChaos(&ChaosConfig{MaxDelay: "soon"}, realClock{}); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+	if _, err := newChaos(&ChaosConfig{Operations: map[string]OperationFaultConfig{"GetPods": {ErrorRate: 1}}}, realClock{}); err == nil {
+		t.Error("Expected an error for an unknown operation")
+	}
+	if _, err := newChaos(&ChaosConfig{Operations: map[string]OperationFaultConfig{operationCreatePod: {ErrorRate: -1}}}, realClock{}); err == nil {
+		t.Error("Expected an error for an invalid error rate")
+	}
+}
+
+func TestChaosOperationFaults(t *testing.T) {
+	clock := newFakeClock()
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	var err error
+	p.chaos, err = newChaos(&ChaosConfig{Operations: map[string]OperationFaultConfig{
+		operationCreatePod:    {ErrorRate: 1},
+		operationDeletePod:    {DeadlineExceededRate: 1},
+		operationGetPodStatus: {Latency: "1s"},
+	}}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err == nil {
+		t.Error("Expected CreatePod to fail")
+	}
+	if err := p.DeletePod(ctx, makePod("default", "foo")); err != context.DeadlineExceeded {
+		t.Errorf("Got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if err := p.UpdatePod(ctx, makePod("default", "foo")); !errors.IsNotFound(err) {
+		t.Errorf("Got error %v, expected UpdatePod to fail with NotFound rather than an injected error", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := p.GetPodStatus(ctx, "default", "foo")
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Expected GetPodStatus to be delayed")
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeterministicFast(t *testing.T) {
+	ctx := context.Background()
+	config := MockConfig{
+		Chaos: &ChaosConfig{
+			KillProbability:          1,
+			CorruptStatusProbability: 1,
+			Operations: map[string]OperationFaultConfig{
+				operationCreatePod: {ErrorRate: 1},
+			},
+		},
+		StartupDelay: &StartupDelayConfig{Sandbox: "1h"},
+		RateLimit:    &RateLimitConfig{QPS: 1, Burst: 1, Reject: true},
+	}
+
+	for _, c := range []struct {
+		name   string
+		config MockConfig
+		opts   []MockProviderOption
+	}{
+		{"config", config, nil},
+		{"option", config, []MockProviderOption{WithDeterministicFast()}},
+	} {
+		if c.opts == nil {
+			c.config.DeterministicFast = boolPtr(true)
+		}
+		p, err := NewMockProviderWithConfig(c.config, "vk", "Linux", "10.0.0.1", 10250, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"foo", "bar"} {
+			pod := makePod("default", name)
+			pod.Annotations = map[string]string{startupDelayAnnotation: "1h"}
+			if err := p.CreatePod(ctx, pod); err != nil {
+				t.Fatalf("%s: Unexpected error creating %s: %v", c.name, name, err)
+			}
+			status, err := p.GetPodStatus(ctx, "default", name)
+			if err != nil {
+				t.Fatalf("%s: Unexpected error getting the status of %s: %v", c.name, name, err)
+			}
+			if status.Phase != v1.PodRunning {
+				t.Errorf("%s: Got phase %s for %s, expected %s", c.name, status.Phase, name, v1.PodRunning)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package mock
+
+import "time"
+
+// Clock is the source of time of the mock provider.
+// It can be replaced with WithClock to control the passing of time, e.g. in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel receiving the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func())
+}
+
+// realClock is the Clock following the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) AfterFunc(d time.Duration, f func())    { time.AfterFunc(d, f) }
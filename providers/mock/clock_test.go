@@ -0,0 +1,117 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// fakeClock is a Clock whose time only passes when it is advanced.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	f  func()
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() { ch <- c.Now() })
+	return ch
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), f: f})
+}
+
+// Advance moves the time forward and calls the functions whose timers expired.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var expired []func()
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			expired = append(expired, t.f)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, f := range expired {
+		f()
+	}
+}
+
+// BlockUntil waits until at least n timers are pending.
+func (c *fakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending := len(c.timers)
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	path := writeConfig(t, `{"defaults": {"startupDelay": {"sandbox": "10s"}}}`)
+	defer os.Remove(path)
+
+	clock := newFakeClock()
+	p, err := NewMockProvider(path, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	accepted := clock.Now()
+
+	for _, step := range []struct {
+		advance time.Duration
+		phase   v1.PodPhase
+	}{
+		{0, v1.PodPending},
+		{9 * time.Second, v1.PodPending},
+		{time.Second, v1.PodRunning},
+	} {
+		clock.Advance(step.advance)
+		status, err := p.GetPodStatus(ctx, "default", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase != step.phase {
+			t.Errorf("Got phase %s at %v, expected %s", status.Phase, clock.Now(), step.phase)
+		}
+		if !status.StartTime.Time.Equal(accepted) {
+			t.Errorf("Got start time %v, expected %v", status.StartTime, accepted)
+		}
+	}
+}
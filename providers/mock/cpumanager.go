@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"fmt"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// cpuPools is the split of the cores of the node between those assigned exclusively to containers
+// and the shared pool the other containers run on.
+type cpuPools struct {
+	Exclusive int64 `json:"exclusive"`
+	Shared    int64 `json:"shared"`
+}
+
+// exclusiveCPUs returns the number of cores the static CPU manager policy assigns exclusively to the pod:
+// those requested by the containers of Guaranteed pods which request an integer number of CPUs.
+// Cores assigned to init containers are reused by the containers which follow them.
+func exclusiveCPUs(pod *v1.Pod, defaults resourceDefaults) int64 {
+	if !isGuaranteed(pod, defaults) {
+		return 0
+	}
+
+	var init, app int64
+	for _, c := range pod.Spec.InitContainers {
+		if n := containerExclusiveCPUs(defaults.apply(c.Resources)); n > init {
+			init = n
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		app += containerExclusiveCPUs(defaults.apply(c.Resources))
+	}
+	if init > app {
+		return init
+	}
+	return app
+}
+
+func containerExclusiveCPUs(r v1.ResourceRequirements) int64 {
+	cpu := r.Requests[v1.ResourceCPU]
+	if cpu.MilliValue()%1000 != 0 {
+		return 0
+	}
+	return cpu.MilliValue() / 1000
+}
+
+// isGuaranteed reports whether the pod is of the Guaranteed QoS class, which is the case when
+// every container has CPU and memory limits, and requests equal to them.
+func isGuaranteed(pod *v1.Pod, defaults resourceDefaults) bool {
+	containers := append(append([]v1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		r := defaults.apply(c.Resources)
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, ok := r.Limits[name]
+			if !ok || limit.IsZero() {
+				return false
+			}
+			if request := r.Requests[name]; request.Cmp(limit) != 0 {
+				return false
+			}
+		}
+	}
+	return len(containers) > 0
+}
+
+// cpuPools returns the current split of the cores of the node, which are the whole CPUs of its capacity.
+// p.mu must be held.
+func (p *MockProvider) cpuPools() cpuPools {
+	var pools cpuPools
+	if p.config.CPUManagerPolicy == cpuManagerPolicyStatic {
+		for key, pod := range p.pods {
+			if !p.holdsResources(key) {
+				continue
+			}
+			pools.Exclusive += exclusiveCPUs(pod, p.resourceDefaults)
+		}
+	}
+
+	capacity := resource.MustParse(p.config.CPU)
+	pools.Shared = capacity.MilliValue()/1000 - pools.Exclusive
+	return pools
+}
+
+// checkExclusiveCPUs returns the rejected status of the pod if the static CPU manager policy cannot assign it
+// the cores it needs exclusively, or nil. Exclusive cores are taken from the shared pool, which must keep
+// at least one core for the other containers.
+// p.mu must be held.
+func (p *MockProvider) checkExclusiveCPUs(pod *v1.Pod) *v1.PodStatus {
+	if p.config.CPUManagerPolicy != cpuManagerPolicyStatic {
+		return nil
+	}
+	n := exclusiveCPUs(pod, p.resourceDefaults)
+	if n == 0 {
+		return nil
+	}
+
+	pools := p.cpuPools()
+	if available := pools.Shared - 1; n > available {
+		if available < 0 {
+			available = 0
+		}
+		return rejectedPodStatus(providers.PodReasonUnexpectedAdmissionError, fmt.Sprintf("Pod was rejected: %s: requested %d, available %d", providers.PodMessageNotEnoughCPUs, n, available))
+	}
+	return nil
+}
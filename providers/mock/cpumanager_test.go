@@ -0,0 +1,102 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func makeGuaranteedPod(name, cpu string) *v1.Pod {
+	pod := makePod("default", name)
+	resources := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	pod.Spec.Containers[0].Resources = v1.ResourceRequirements{Requests: resources, Limits: resources.DeepCopy()}
+	return pod
+}
+
+func TestExclusiveCPUs(t *testing.T) {
+	burstable := makeGuaranteedPod("burstable", "2")
+	burstable.Spec.Containers[0].Resources.Requests[v1.ResourceMemory] = resource.MustParse("512Mi")
+
+	withInit := makeGuaranteedPod("init", "1")
+	withInit.Spec.InitContainers = []v1.Container{makeGuaranteedPod("", "3").Spec.Containers[0]}
+
+	for _, c := range []struct {
+		pod      *v1.Pod
+		expected int64
+	}{
+		{makePod("default", "best-effort"), 0},
+		{burstable, 0},
+		{makeGuaranteedPod("fractional", "1500m"), 0},
+		{makeGuaranteedPod("integer", "2"), 2},
+		{withInit, 3},
+	} {
+		if n := exclusiveCPUs(c.pod, resourceDefaults{}); n != c.expected {
+			t.Errorf("Got %d exclusive cpus for %s, expected %d", n, c.pod.Name, c.expected)
+		}
+	}
+}
+
+func TestCPUManagerStatic(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewMockProviderWithConfig(MockConfig{CPU: "4", CPUManagerPolicy: "static"}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		pod      *v1.Pod
+		expected v1.PodPhase
+	}{
+		{makeGuaranteedPod("foo", "2"), v1.PodPending},
+		{makeGuaranteedPod("bar", "2"), v1.PodFailed},
+		{makeGuaranteedPod("baz", "1"), v1.PodPending},
+		{makeGuaranteedPod("qux", "500m"), v1.PodPending},
+	} {
+		if err := p.CreatePod(ctx, c.pod); err != nil {
+			t.Fatal(err)
+		}
+		status, err := p.GetPodStatus(ctx, "default", c.pod.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase != c.expected && !(c.expected == v1.PodPending && status.Phase == v1.PodRunning) {
+			t.Errorf("Got phase %s for %s, expected %s", status.Phase, c.pod.Name, c.expected)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/cpus", nil)
+	w := httptest.NewRecorder()
+	p.adminHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", w.Code, http.StatusOK)
+	}
+	var pools cpuPools
+	if err := json.NewDecoder(w.Body).Decode(&pools); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (cpuPools{Exclusive: 3, Shared: 1}); pools != expected {
+		t.Errorf("Got cpu pools %+v, expected %+v", pools, expected)
+	}
+
+	if err := p.DeletePod(ctx, makeGuaranteedPod("foo", "2")); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.RLock()
+	pools = p.cpuPools()
+	p.mu.RUnlock()
+	if expected := (cpuPools{Exclusive: 1, Shared: 3}); pools != expected {
+		t.Errorf("Got cpu pools %+v after deletion, expected %+v", pools, expected)
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{CPUManagerPolicy: "dynamic"}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an invalid CPU manager policy")
+	}
+}
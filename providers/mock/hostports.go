@@ -0,0 +1,85 @@
+package mock
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// hostPortConflictReason is the reason of the status of pods rejected because of a host port
+// conflict, which is the one reported by the kubelet.
+const hostPortConflictReason = "PodFitsHostPorts"
+
+// hostPort is a port of the node declared by a container.
+type hostPort struct {
+	ip       string
+	protocol v1.Protocol
+	port     int32
+}
+
+func (hp hostPort) String() string {
+	return fmt.Sprintf("%s:%d/%s", hp.ip, hp.port, hp.protocol)
+}
+
+// conflicts reports whether two host ports cannot be used at the same time.
+// An unspecified IP binds every address of the node.
+func (hp hostPort) conflicts(other hostPort) bool {
+	if hp.port != other.port || hp.protocol != other.protocol {
+		return false
+	}
+	return hp.ip == other.ip || isAnyIP(hp.ip) || isAnyIP(other.ip)
+}
+
+func isAnyIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// podHostPorts returns the host ports declared by the containers of the pod.
+func podHostPorts(pod *v1.Pod) []hostPort {
+	var ports []hostPort
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.HostPort <= 0 {
+				continue
+			}
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
+			ports = append(ports, hostPort{ip: p.HostIP, protocol: protocol, port: p.HostPort})
+		}
+	}
+	return ports
+}
+
+// findHostPortConflict returns a host port of the pod already used by another pod which still holds its resources.
+// p.mu must be held.
+func (p *MockProvider) findHostPortConflict(key string, pod *v1.Pod) (hostPort, bool) {
+	ports := podHostPorts(pod)
+	if len(ports) == 0 {
+		return hostPort{}, false
+	}
+
+	for k, other := range p.pods {
+		if k == key || !p.holdsResources(k) {
+			continue
+		}
+		for _, used := range podHostPorts(other) {
+			for _, hp := range ports {
+				if hp.conflicts(used) {
+					return hp, true
+				}
+			}
+		}
+	}
+	return hostPort{}, false
+}
+
+// rejectedPodStatus builds the status of a pod which was rejected before any of its containers was started.
+func rejectedPodStatus(reason, message string) *v1.PodStatus {
+	return &v1.PodStatus{
+		Phase:   v1.PodFailed,
+		Reason:  reason,
+		Message: message,
+	}
+}
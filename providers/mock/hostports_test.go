@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func makePodWithHostPort(name, hostIP string, port int32) *v1.Pod {
+	pod := makePod("default", name)
+	pod.Spec.Containers[0].Ports = []v1.ContainerPort{{ContainerPort: 80, HostPort: port, HostIP: hostIP}}
+	return pod
+}
+
+func TestHostPortConflict(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	for _, pod := range []*v1.Pod{
+		makePodWithHostPort("foo", "", 8080),
+		makePodWithHostPort("bar", "", 8081),
+		makePodWithHostPort("baz", "10.0.0.1", 8080),
+	} {
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, expected := range map[string]v1.PodPhase{"foo": v1.PodRunning, "bar": v1.PodRunning, "baz": v1.PodFailed} {
+		status, err := p.GetPodStatus(ctx, "default", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase != expected {
+			t.Errorf("Got phase %s for %s, expected %s", status.Phase, name, expected)
+		}
+	}
+
+	status, err := p.GetPodStatus(ctx, "default", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Reason != hostPortConflictReason {
+		t.Errorf("Got reason %s, expected %s", status.Reason, hostPortConflictReason)
+	}
+
+	if err := p.DeletePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreatePod(ctx, makePodWithHostPort("qux", "10.0.0.1", 8080)); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := p.GetPodStatus(ctx, "default", "qux"); err != nil {
+		t.Fatal(err)
+	} else if status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s once the host port is released", status.Phase, v1.PodRunning)
+	}
+}
+
+func TestHostPortConflicts(t *testing.T) {
+	cases := []struct {
+		a, b     hostPort
+		conflict bool
+	}{
+		{hostPort{"", v1.ProtocolTCP, 80}, hostPort{"10.0.0.1", v1.ProtocolTCP, 80}, true},
+		{hostPort{"10.0.0.1", v1.ProtocolTCP, 80}, hostPort{"10.0.0.2", v1.ProtocolTCP, 80}, false},
+		{hostPort{"", v1.ProtocolTCP, 80}, hostPort{"", v1.ProtocolUDP, 80}, false},
+		{hostPort{"", v1.ProtocolTCP, 80}, hostPort{"", v1.ProtocolTCP, 81}, false},
+	}
+	for _, c := range cases {
+		if got := c.a.conflicts(c.b); got != c.conflict {
+			t.Errorf("Got %v for %s and %s, expected %v", got, c.a, c.b, c.conflict)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errPodCIDRExhausted is returned when every address of the pod CIDR is allocated.
+var errPodCIDRExhausted = errors.New("no pod IP available in the pod CIDR")
+
+// podIPAllocator allocates unique pod IPs from an IPv4 CIDR.
+// The network and broadcast addresses are never allocated.
+// It is not safe for concurrent use.
+type podIPAllocator struct {
+	base uint32
+	size uint32
+	next uint32
+	used map[uint32]bool
+}
+
+func newPodIPAllocator(cidr string) (*podIPAllocator, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil || network.IP.To4() == nil {
+		return nil, fmt.Errorf("Invalid pod CIDR %v", cidr)
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("Invalid pod CIDR %v: too small", cidr)
+	}
+
+	return &podIPAllocator{
+		base: binary.BigEndian.Uint32(network.IP.To4()),
+		size: uint32(1) << uint(bits-ones),
+		next: 1,
+		used: make(map[uint32]bool),
+	}, nil
+}
+
+// allocate returns an address which is not in use.
+// Addresses are handed out in turn, so that a released address is not reused right away.
+func (a *podIPAllocator) allocate() (string, error) {
+	for i := uint32(0); i < a.size-2; i++ {
+		offset := a.next
+		a.next++
+		if a.next >= a.size-1 {
+			a.next = 1
+		}
+
+		if !a.used[offset] {
+			a.used[offset] = true
+			return a.ip(offset), nil
+		}
+	}
+	return "", errPodCIDRExhausted
+}
+
+// release makes an allocated address available again.
+func (a *podIPAllocator) release(ip string) {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return
+	}
+	delete(a.used, binary.BigEndian.Uint32(parsed)-a.base)
+}
+
+func (a *podIPAllocator) ip(offset uint32) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, a.base+offset)
+	return ip.String()
+}
@@ -0,0 +1,42 @@
+package mock
+
+import (
+	"testing"
+)
+
+func TestPodIPAllocator(t *testing.T) {
+	a, err := newPodIPAllocator("10.0.0.0/30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"10.0.0.1", "10.0.0.2"} {
+		ip, err := a.allocate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip != expected {
+			t.Errorf("Got %s, expected %s", ip, expected)
+		}
+	}
+	if _, err := a.allocate(); err != errPodCIDRExhausted {
+		t.Errorf("Got error %v, expected %v", err, errPodCIDRExhausted)
+	}
+
+	a.release("10.0.0.2")
+	ip, err := a.allocate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "10.0.0.2" {
+		t.Errorf("Got %s, expected 10.0.0.2", ip)
+	}
+}
+
+func TestPodIPAllocatorInvalidCIDR(t *testing.T) {
+	for _, cidr := range []string{"", "10.0.0.0", "10.0.0.0/31", "fd00::/64"} {
+		if _, err := newPodIPAllocator(cidr); err == nil {
+			t.Errorf("Expected an error for pod CIDR %q", cidr)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package mock
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultResourcesConfig configures the requests and limits given to containers which declare none,
+// like a LimitRange does. Quantities are keyed by resource name.
+type DefaultResourcesConfig struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// resourceDefaults are the parsed DefaultResourcesConfig.
+type resourceDefaults struct {
+	requests v1.ResourceList
+	limits   v1.ResourceList
+}
+
+func newResourceDefaults(config *DefaultResourcesConfig) (resourceDefaults, error) {
+	var d resourceDefaults
+	if config == nil {
+		return d, nil
+	}
+
+	var err error
+	if d.requests, err = parseResourceList(config.Requests); err != nil {
+		return d, fmt.Errorf("Invalid default request %v", err)
+	}
+	if d.limits, err = parseResourceList(config.Limits); err != nil {
+		return d, fmt.Errorf("Invalid default limit %v", err)
+	}
+	return d, nil
+}
+
+func parseResourceList(values map[string]string) (v1.ResourceList, error) {
+	list := make(v1.ResourceList, len(values))
+	for name, value := range values {
+		q, err := resource.ParseQuantity(value)
+		if err != nil || q.Sign() < 0 {
+			return nil, fmt.Errorf("%s %v", name, value)
+		}
+		list[v1.ResourceName(name)] = q
+	}
+	return list, nil
+}
+
+// apply returns the resource requirements of a container once defaulted: the default limit of a resource
+// is given to containers without a limit for it, and the default request to containers without a request
+// for it. Like the API server does, a resource which has a limit but no request is then requested up to its limit.
+func (d resourceDefaults) apply(r v1.ResourceRequirements) v1.ResourceRequirements {
+	defaulted := v1.ResourceRequirements{Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+	for name, q := range r.Limits {
+		defaulted.Limits[name] = q
+	}
+	for name, q := range r.Requests {
+		defaulted.Requests[name] = q
+	}
+
+	for name, q := range d.limits {
+		if _, ok := defaulted.Limits[name]; !ok {
+			defaulted.Limits[name] = q
+		}
+	}
+	for name, q := range d.requests {
+		if _, ok := defaulted.Requests[name]; !ok {
+			defaulted.Requests[name] = q
+		}
+	}
+	for name, q := range defaulted.Limits {
+		if _, ok := defaulted.Requests[name]; !ok {
+			defaulted.Requests[name] = q
+		}
+	}
+	return defaulted
+}
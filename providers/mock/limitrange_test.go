@@ -0,0 +1,74 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestResourceDefaultsApply(t *testing.T) {
+	d, err := newResourceDefaults(&DefaultResourcesConfig{
+		Requests: map[string]string{"cpu": "100m"},
+		Limits:   map[string]string{"memory": "256Mi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := d.apply(v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+	})
+	for _, c := range []struct {
+		list     v1.ResourceList
+		name     v1.ResourceName
+		expected string
+	}{
+		{r.Requests, v1.ResourceCPU, "1"},
+		{r.Requests, v1.ResourceMemory, "256Mi"},
+		{r.Limits, v1.ResourceMemory, "256Mi"},
+	} {
+		if q := c.list[c.name]; q.String() != c.expected {
+			t.Errorf("Got %s %s, expected %s", c.name, q.String(), c.expected)
+		}
+	}
+	if _, ok := r.Limits[v1.ResourceCPU]; ok {
+		t.Error("Expected no cpu limit")
+	}
+
+	if _, err := newResourceDefaults(&DefaultResourcesConfig{Limits: map[string]string{"cpu": "lots"}}); err == nil {
+		t.Error("Expected an error for an invalid quantity")
+	}
+}
+
+func TestDefaultResourcesQuota(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewMockProviderWithConfig(MockConfig{
+		NamespaceQuotas:  map[string]map[string]string{"default": {"requests.cpu": "1"}},
+		DefaultResources: &DefaultResourcesConfig{Requests: map[string]string{"cpu": "500m"}},
+	}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if err := p.CreatePod(ctx, makePod("default", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var failed int
+	for _, name := range []string{"foo", "bar", "baz"} {
+		status, err := p.GetPodStatus(ctx, "default", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase == v1.PodFailed {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("Got %d pods rejected, expected the BestEffort pods to be accounted with the default request", failed)
+	}
+}
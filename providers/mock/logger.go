@@ -0,0 +1,13 @@
+package mock
+
+import "log"
+
+// Logger is the logger of the mock provider, which *log.Logger and logrus loggers implement.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// stdLogger is the Logger writing to the standard logger of the log package.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, args ...interface{}) { log.Printf(format, args...) }
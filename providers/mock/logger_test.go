@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	path := writeConfig(t, `{"defaults": {}}`)
+	defer os.Remove(path)
+
+	var buf bytes.Buffer
+	p, err := NewMockProvider(path, "vk", "Linux", "10.0.0.1", 10250, WithLogger(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreatePod(context.Background(), makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `receive CreatePod "foo"`) {
+		t.Errorf("Got log %q, expected the CreatePod call to be logged", buf.String())
+	}
+}
@@ -6,31 +6,86 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
+	"net"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/remotecommand"
 )
 
+// Reasons of the Ready and ContainersReady conditions of pods which are not ready,
+// which are the ones reported by the kubelet.
+const (
+	containersNotReadyReason = "ContainersNotReady"
+	podCompletedReason       = "PodCompleted"
+)
+
 const (
 	// Provider configuration defaults.
 	defaultCPUCapacity    = "20"
 	defaultMemoryCapacity = "100Gi"
 	defaultPodCapacity    = "20"
+	defaultPodCIDR        = "10.244.0.0/16"
+
+	// Policies for CreatePod calls on a pod which already exists.
+	duplicateCreateUpdate = "update"
+	duplicateCreateIgnore = "ignore"
+	duplicateCreateReject = "reject"
+
+	// CPU manager policies. The static policy assigns cores exclusively to the containers of Guaranteed
+	// pods requesting an integer number of CPUs.
+	cpuManagerPolicyNone   = "none"
+	cpuManagerPolicyStatic = "static"
+
+	// defaultsConfigKey is the entry of the config file which is merged into
+	// the config of every node.
+	defaultsConfigKey = "defaults"
 )
 
 // MockProvider implements the virtual-kubelet provider interface and stores pods in memory.
 type MockProvider struct {
+	mu                 sync.RWMutex
 	nodeName           string
 	operatingSystem    string
 	internalIP         string
 	daemonEndpointPort int32
 	pods               map[string]*v1.Pod
+	terminated         map[string]*v1.ContainerStateTerminated
+	terminating        map[string]podDeletion
+	rejected           map[string]*v1.PodStatus
+	starting           map[string]podStartup
+	startupDelay       startupDelay
+	terminatedPodTTL   time.Duration
+	podIPs             map[string]string
+	startTimes         map[string]metav1.Time
+	ipam               *podIPAllocator
+	conditions         map[v1.NodeConditionType]conditionOverride
+	transitions        map[v1.NodeConditionType]conditionTransition
+	failures           map[string]injectedFailure
+	quotas             map[string]v1.ResourceList
+	usage              map[string]*namespaceUsage
+	accounted          map[string]accountedPod
+	resourceDefaults   resourceDefaults
+	overheads          map[string]v1.ResourceList
+	chaos              *chaos
+	rateLimiter        *rateLimiter
+	recorder           *recorder
+	auditor            *recorder
+	notifier           func(*v1.Pod)
+	clock              Clock
+	logger             Logger
+	leasePaused        bool
+	adminServer        *http.Server
+	adminDone          chan struct{}
+	stopReplay         context.CancelFunc
+	replayDone         chan struct{}
 	config             MockConfig
 }
 
@@ -39,28 +94,271 @@ type MockConfig struct {
 	CPU    string `json:"cpu,omitempty"`
 	Memory string `json:"memory,omitempty"`
 	Pods   string `json:"pods,omitempty"`
+
+	// HugePages is the capacity of huge pages keyed by page size, e.g. 2Mi or 1Gi, which is reported
+	// as the hugepages-<size> resources of the node.
+	HugePages map[string]string `json:"hugePages,omitempty"`
+
+	// AdminAddr is the address the admin HTTP server listens on.
+	// The admin server is not started if it is empty.
+	AdminAddr string `json:"adminAddr,omitempty"`
+
+	// Chaos configures faults randomly injected by the provider.
+	// No fault is injected if it is not set.
+	Chaos *ChaosConfig `json:"chaos,omitempty"`
+
+	// RecordPath is the trace file pod operations received by the provider are appended to.
+	RecordPath string `json:"recordPath,omitempty"`
+
+	// AuditPath is the audit file the pod operations of the provider are appended to once completed,
+	// with their arguments and results. Audit files can be replayed like trace files.
+	AuditPath string `json:"auditPath,omitempty"`
+
+	// ReplayPath is a trace file whose pod operations are fed back into the provider at startup.
+	// The intervals between operations are divided by ReplaySpeed, which defaults to 1.
+	// Replayed pods only exist in the provider: the API server doesn't know them, so virtual-kubelet
+	// deletes those replayed before its startup reconciliation and never syncs the status of the others.
+	// It can't be the RecordPath or the AuditPath.
+	ReplayPath  string  `json:"replayPath,omitempty"`
+	ReplaySpeed float64 `json:"replaySpeed,omitempty"`
+
+	// GracefulDeletion makes DeletePod keep the pod terminating for its deletion grace period before removing it.
+	// DeletePod returns right away, and the terminated pod is pushed to the PodNotifier once it is removed.
+	// The pod IP, host ports, quota usage and exclusive CPUs of the pod are released when its deletion starts.
+	// Like DisableHostNetwork, it is a pointer so that a node can turn off the option set in the defaults.
+	GracefulDeletion *bool `json:"gracefulDeletion,omitempty"`
+
+	// OnDuplicateCreate is what CreatePod does with a pod which already exists: "update" replaces
+	// its definition, "ignore" leaves it unchanged and "reject" fails with an AlreadyExists error.
+	// Either way, the status of the pod is left unchanged. It defaults to "update". Updates of existing pods
+	// never reach the provider: virtual-kubelet only calls CreatePod again for a pod when reconciling the pods
+	// at startup, or when retrying a pod waiting for its Secrets and ConfigMaps.
+	OnDuplicateCreate string `json:"onDuplicateCreate,omitempty"`
+
+	// DisableHostNetwork makes the provider reject pods using the host network.
+	DisableHostNetwork *bool `json:"disableHostNetwork,omitempty"`
+
+	// EnforceCapacity makes the provider reject pods whose requests exceed the capacity of the node left by
+	// the other pods, with the OutOf<resource> reasons of the kubelet, e.g. OutOfcpu or OutOfpods.
+	// Like the kubelet, critical pods are admitted even when the node is full: pods owned by a DaemonSet,
+	// and pods with a system critical priority. DisableCriticalPodAdmission makes them rejected too.
+	EnforceCapacity             *bool `json:"enforceCapacity,omitempty"`
+	DisableCriticalPodAdmission *bool `json:"disableCriticalPodAdmission,omitempty"`
+
+	// NamespaceQuotas are the hard limits of the resources held by the pods of each namespace, keyed by
+	// namespace and then by resource name, with the names of ResourceQuotas, e.g. pods, requests.cpu or
+	// limits.memory. Pods which would exceed the quota of their namespace are rejected.
+	NamespaceQuotas map[string]map[string]string `json:"namespaceQuotas,omitempty"`
+
+	// DefaultResources are given to containers which declare no requests or limits before their pods
+	// are validated and accounted, like a LimitRange does. The pods themselves are left unchanged.
+	DefaultResources *DefaultResourcesConfig `json:"defaultResources,omitempty"`
+
+	// RuntimeClassOverheads are the resources taken by the sandboxes of pods on top of their containers,
+	// keyed by runtime class and then by resource name. The overhead of the runtime class of a pod is
+	// added to its accounted requests, and to the limits of the resources its containers limit.
+	RuntimeClassOverheads map[string]map[string]string `json:"runtimeClassOverheads,omitempty"`
+
+	// CPUManagerPolicy is "none", the default, or "static", which makes the provider track the cores
+	// assigned exclusively to pods, and reject pods once exclusive cores run out.
+	CPUManagerPolicy string `json:"cpuManagerPolicy,omitempty"`
+
+	// PodCIDR is the IPv4 range the IPs of the pods are allocated from, which is reported as the PodCIDR
+	// of the node.
+	PodCIDR string `json:"podCIDR,omitempty"`
+
+	// InternalIPs, ExternalIPs and Hostname are reported as addresses of the node, in addition to
+	// the internal IP virtual-kubelet is started with.
+	InternalIPs []string `json:"internalIPs,omitempty"`
+	ExternalIPs []string `json:"externalIPs,omitempty"`
+	Hostname    string   `json:"hostname,omitempty"`
+
+	// NodeInfo overrides the system info reported in the node status. The operating system
+	// must be Linux or Windows, and defaults to Linux. Its fields are merged with the defaults one by one.
+	NodeInfo NodeInfoConfig `json:"nodeInfo,omitempty"`
+
+	// StartupDelay configures the time pods stay pending before they are reported running.
+	// Pods start right away if it is not set. The mock.virtual-kubelet.io/startup-delay annotation
+	// overrides it for a pod.
+	StartupDelay *StartupDelayConfig `json:"startupDelay,omitempty"`
+
+	// RateLimit limits the rate of the pod operations. They are not limited if it is not set.
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// DeterministicFast turns off every perturbation of the simulation at once, for clean baseline runs to
+	// compare perturbed runs with: Chaos, including the latency and errors injected into operations,
+	// StartupDelay and the startup delay annotation, and RateLimit are ignored. The workload model, i.e.
+	// the capacity, quotas, admission and accounting of the node, is kept.
+	DeterministicFast *bool `json:"deterministicFast,omitempty"`
+
+	// TerminatedPodTTL is how long terminated and rejected pods are kept by the provider, parsed by
+	// time.ParseDuration. Pruned pods are no longer returned by the provider, but they stay in Kubernetes
+	// until they are deleted there. Pods are kept until they are deleted if it is not set.
+	TerminatedPodTTL string `json:"terminatedPodTTL,omitempty"`
+}
+
+// NodeInfoConfig is the system info of a mock node. Fields left empty are filled with the
+// defaults of virtual-kubelet.
+type NodeInfoConfig struct {
+	OperatingSystem         string `json:"operatingSystem,omitempty"`
+	Architecture            string `json:"architecture,omitempty"`
+	KernelVersion           string `json:"kernelVersion,omitempty"`
+	OSImage                 string `json:"osImage,omitempty"`
+	KubeletVersion          string `json:"kubeletVersion,omitempty"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion,omitempty"`
 }
 
 // NewMockProvider creates a new MockProvider
-func NewMockProvider(providerConfig, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32) (*MockProvider, error) {
+func NewMockProvider(providerConfig, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32, opts ...MockProviderOption) (*MockProvider, error) {
 	config, err := loadConfig(providerConfig, nodeName)
 	if err != nil {
 		return nil, err
 	}
 
+	return newMockProvider(config, nodeName, operatingSystem, internalIP, daemonEndpointPort, opts...)
+}
+
+// NewMockProviderWithConfig creates a new MockProvider with the given config instead of loading it from a file.
+// Fields of the config which are not set take their default values.
+func NewMockProviderWithConfig(config MockConfig, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32, opts ...MockProviderOption) (*MockProvider, error) {
+	setConfigDefaults(&config)
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return newMockProvider(config, nodeName, operatingSystem, internalIP, daemonEndpointPort, opts...)
+}
+
+func newMockProvider(config MockConfig, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32, opts ...MockProviderOption) (*MockProvider, error) {
+	ipam, err := newPodIPAllocator(config.PodCIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := parseQuotas(config.NamespaceQuotas)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceDefaults, err := newResourceDefaults(config.DefaultResources)
+	if err != nil {
+		return nil, err
+	}
+
+	overheads, err := parseOverheads(config.RuntimeClassOverheads)
+	if err != nil {
+		return nil, err
+	}
+
+	var terminatedPodTTL time.Duration
+	if config.TerminatedPodTTL != "" {
+		if terminatedPodTTL, err = time.ParseDuration(config.TerminatedPodTTL); err != nil || terminatedPodTTL <= 0 {
+			return nil, fmt.Errorf("Invalid terminated pod TTL %v", config.TerminatedPodTTL)
+		}
+	}
+
 	provider := MockProvider{
 		nodeName:           nodeName,
 		operatingSystem:    operatingSystem,
 		internalIP:         internalIP,
 		daemonEndpointPort: daemonEndpointPort,
 		pods:               make(map[string]*v1.Pod),
+		terminated:         make(map[string]*v1.ContainerStateTerminated),
+		terminating:        make(map[string]podDeletion),
+		rejected:           make(map[string]*v1.PodStatus),
+		starting:           make(map[string]podStartup),
+		terminatedPodTTL:   terminatedPodTTL,
+		podIPs:             make(map[string]string),
+		startTimes:         make(map[string]metav1.Time),
+		ipam:               ipam,
+		conditions:         make(map[v1.NodeConditionType]conditionOverride),
+		transitions:        make(map[v1.NodeConditionType]conditionTransition),
+		failures:           make(map[string]injectedFailure),
+		quotas:             quotas,
+		usage:              make(map[string]*namespaceUsage),
+		accounted:          make(map[string]accountedPod),
+		resourceDefaults:   resourceDefaults,
+		overheads:          overheads,
+		clock:              realClock{},
+		logger:             stdLogger{},
 		config:             config,
 	}
+	for _, opt := range opts {
+		opt(&provider)
+	}
+
+	if isEnabled(provider.config.DeterministicFast) {
+		provider.config.Chaos = nil
+		provider.config.StartupDelay = nil
+		provider.config.RateLimit = nil
+	}
+	if provider.startupDelay, err = newStartupDelay(provider.config.StartupDelay, isEnabled(provider.config.DeterministicFast)); err != nil {
+		return nil, err
+	}
+	if provider.chaos, err = newChaos(provider.config.Chaos, provider.clock); err != nil {
+		return nil, err
+	}
+	if provider.rateLimiter, err = newRateLimiter(provider.config.RateLimit, provider.clock); err != nil {
+		return nil, err
+	}
+	if provider.recorder, err = newRecorder(config.RecordPath, provider.clock, provider.logger); err != nil {
+		return nil, err
+	}
+	if provider.auditor, err = newRecorder(config.AuditPath, provider.clock, provider.logger); err != nil {
+		provider.Close()
+		return nil, err
+	}
+
+	if config.AdminAddr != "" {
+		if err := provider.startAdminServer(config.AdminAddr); err != nil {
+			provider.Close()
+			return nil, err
+		}
+	}
+
+	if config.ReplayPath != "" {
+		if err := provider.startReplay(config.ReplayPath, config.ReplaySpeed); err != nil {
+			// Release the trace and audit files and the admin listener opened above.
+			provider.Close()
+			return nil, err
+		}
+	}
+
 	return &provider, nil
 }
 
-// loadConfig loads the given json configuration files.
+// Close stops the admin server and the replay of the trace file, and closes the trace and audit files.
+// The first error encountered is returned. Pods are kept, but their statuses are no longer pushed to virtual-kubelet.
+func (p *MockProvider) Close() error {
+	var closeErr error
+
+	if p.adminServer != nil {
+		if err := p.adminServer.Close(); err != nil {
+			closeErr = fmt.Errorf("error closing admin server: %v", err)
+		}
+		<-p.adminDone
+	}
+
+	if p.stopReplay != nil {
+		p.stopReplay()
+		<-p.replayDone
+	}
 
+	p.mu.Lock()
+	p.notifier = nil
+	p.mu.Unlock()
+
+	for _, r := range []*recorder{p.recorder, p.auditor} {
+		if err := r.close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+// loadConfig loads the given json configuration files.
+// The config of the node is merged with the "defaults" entry, if any.
 func loadConfig(providerConfig, nodeName string) (config MockConfig, err error) {
 	data, err := ioutil.ReadFile(providerConfig)
 	if err != nil {
@@ -71,82 +369,558 @@ func loadConfig(providerConfig, nodeName string) (config MockConfig, err error)
 	if err != nil {
 		return config, err
 	}
-	if _, exist := configMap[nodeName]; exist {
-		config = configMap[nodeName]
-		if config.CPU == "" {
-			config.CPU = defaultCPUCapacity
+	nodeConfig, nodeExist := configMap[nodeName]
+	defaults, defaultsExist := configMap[defaultsConfigKey]
+	if nodeExist || defaultsExist {
+		config = mergeConfig(nodeConfig, defaults)
+		setConfigDefaults(&config)
+	}
+
+	return config, validateConfig(config)
+}
+
+// setConfigDefaults sets the fields of the config which are not set to their default values.
+func setConfigDefaults(config *MockConfig) {
+	if config.CPU == "" {
+		config.CPU = defaultCPUCapacity
+	}
+	if config.Memory == "" {
+		config.Memory = defaultMemoryCapacity
+	}
+	if config.Pods == "" {
+		config.Pods = defaultPodCapacity
+	}
+	if config.ReplaySpeed == 0 {
+		config.ReplaySpeed = 1
+	}
+	if config.PodCIDR == "" {
+		config.PodCIDR = defaultPodCIDR
+	}
+	if config.OnDuplicateCreate == "" {
+		config.OnDuplicateCreate = duplicateCreateUpdate
+	}
+	if config.CPUManagerPolicy == "" {
+		config.CPUManagerPolicy = cpuManagerPolicyNone
+	}
+}
+
+// validateConfig checks the values of the config.
+func validateConfig(config MockConfig) error {
+	if _, err := resource.ParseQuantity(config.CPU); err != nil {
+		return fmt.Errorf("Invalid CPU value %v", config.CPU)
+	}
+	if _, err := resource.ParseQuantity(config.Memory); err != nil {
+		return fmt.Errorf("Invalid memory value %v", config.Memory)
+	}
+	if _, err := resource.ParseQuantity(config.Pods); err != nil {
+		return fmt.Errorf("Invalid pods value %v", config.Pods)
+	}
+	for size, value := range config.HugePages {
+		if q, err := resource.ParseQuantity(size); err != nil || q.Sign() <= 0 {
+			return fmt.Errorf("Invalid huge page size %v", size)
 		}
-		if config.Memory == "" {
-			config.Memory = defaultMemoryCapacity
+		if q, err := resource.ParseQuantity(value); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("Invalid hugepages-%s value %v", size, value)
+		}
+	}
+	if config.ReplaySpeed < 0 {
+		return fmt.Errorf("Invalid replay speed %v", config.ReplaySpeed)
+	}
+	if config.ReplayPath != "" && (samePath(config.ReplayPath, config.RecordPath) || samePath(config.ReplayPath, config.AuditPath)) {
+		return fmt.Errorf("Invalid replay path %v, it is also written to", config.ReplayPath)
+	}
+	switch config.OnDuplicateCreate {
+	case duplicateCreateUpdate, duplicateCreateIgnore, duplicateCreateReject:
+	default:
+		return fmt.Errorf("Invalid onDuplicateCreate policy %v", config.OnDuplicateCreate)
+	}
+	switch config.CPUManagerPolicy {
+	case cpuManagerPolicyNone, cpuManagerPolicyStatic:
+	default:
+		return fmt.Errorf("Invalid CPU manager policy %v", config.CPUManagerPolicy)
+	}
+	if os := config.NodeInfo.OperatingSystem; os != "" && !providers.ValidOperatingSystems[os] {
+		return fmt.Errorf("Invalid operating system %v, expected one of %v", os, providers.ValidOperatingSystems.Names())
+	}
+	for _, ip := range config.InternalIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("Invalid internal IP %v", ip)
 		}
-		if config.Pods == "" {
-			config.Pods = defaultPodCapacity
+	}
+	for _, ip := range config.ExternalIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("Invalid external IP %v", ip)
 		}
 	}
+	return nil
+}
+
+// mergeConfig fills the fields of config which are not set with the ones of defaults.
+func mergeConfig(config, defaults MockConfig) MockConfig {
+	if config.CPU == "" {
+		config.CPU = defaults.CPU
+	}
+	if config.Memory == "" {
+		config.Memory = defaults.Memory
+	}
+	if config.Pods == "" {
+		config.Pods = defaults.Pods
+	}
+	if config.HugePages == nil {
+		config.HugePages = defaults.HugePages
+	}
+	if config.AdminAddr == "" {
+		config.AdminAddr = defaults.AdminAddr
+	}
+	if config.Chaos == nil {
+		config.Chaos = defaults.Chaos
+	}
+	if config.RecordPath == "" {
+		config.RecordPath = defaults.RecordPath
+	}
+	if config.AuditPath == "" {
+		config.AuditPath = defaults.AuditPath
+	}
+	if config.ReplayPath == "" {
+		config.ReplayPath = defaults.ReplayPath
+	}
+	if config.ReplaySpeed == 0 {
+		config.ReplaySpeed = defaults.ReplaySpeed
+	}
+	if config.GracefulDeletion == nil {
+		config.GracefulDeletion = defaults.GracefulDeletion
+	}
+	if config.OnDuplicateCreate == "" {
+		config.OnDuplicateCreate = defaults.OnDuplicateCreate
+	}
+	if config.DisableHostNetwork == nil {
+		config.DisableHostNetwork = defaults.DisableHostNetwork
+	}
+	if config.EnforceCapacity == nil {
+		config.EnforceCapacity = defaults.EnforceCapacity
+	}
+	if config.DisableCriticalPodAdmission == nil {
+		config.DisableCriticalPodAdmission = defaults.DisableCriticalPodAdmission
+	}
+	if config.NamespaceQuotas == nil {
+		config.NamespaceQuotas = defaults.NamespaceQuotas
+	}
+	if config.DefaultResources == nil {
+		config.DefaultResources = defaults.DefaultResources
+	}
+	if config.RuntimeClassOverheads == nil {
+		config.RuntimeClassOverheads = defaults.RuntimeClassOverheads
+	}
+	if config.CPUManagerPolicy == "" {
+		config.CPUManagerPolicy = defaults.CPUManagerPolicy
+	}
+	if config.PodCIDR == "" {
+		config.PodCIDR = defaults.PodCIDR
+	}
+	if config.InternalIPs == nil {
+		config.InternalIPs = defaults.InternalIPs
+	}
+	if config.ExternalIPs == nil {
+		config.ExternalIPs = defaults.ExternalIPs
+	}
+	if config.Hostname == "" {
+		config.Hostname = defaults.Hostname
+	}
+	config.NodeInfo = mergeNodeInfo(config.NodeInfo, defaults.NodeInfo)
+	if config.StartupDelay == nil {
+		config.StartupDelay = defaults.StartupDelay
+	}
+	if config.RateLimit == nil {
+		config.RateLimit = defaults.RateLimit
+	}
+	if config.DeterministicFast == nil {
+		config.DeterministicFast = defaults.DeterministicFast
+	}
+	if config.TerminatedPodTTL == "" {
+		config.TerminatedPodTTL = defaults.TerminatedPodTTL
+	}
+	return config
+}
 
-	if _, err = resource.ParseQuantity(config.CPU); err != nil {
-		return config, fmt.Errorf("Invalid CPU value %v", config.CPU)
+// mergeNodeInfo fills the fields of info which are not set with the ones of defaults.
+func mergeNodeInfo(info, defaults NodeInfoConfig) NodeInfoConfig {
+	if info.OperatingSystem == "" {
+		info.OperatingSystem = defaults.OperatingSystem
+	}
+	if info.Architecture == "" {
+		info.Architecture = defaults.Architecture
+	}
+	if info.KernelVersion == "" {
+		info.KernelVersion = defaults.KernelVersion
 	}
-	if _, err = resource.ParseQuantity(config.Memory); err != nil {
-		return config, fmt.Errorf("Invalid memory value %v", config.Memory)
+	if info.OSImage == "" {
+		info.OSImage = defaults.OSImage
 	}
-	if _, err = resource.ParseQuantity(config.Pods); err != nil {
-		return config, fmt.Errorf("Invalid pods value %v", config.Pods)
+	if info.KubeletVersion == "" {
+		info.KubeletVersion = defaults.KubeletVersion
 	}
-	return config, nil
+	if info.ContainerRuntimeVersion == "" {
+		info.ContainerRuntimeVersion = defaults.ContainerRuntimeVersion
+	}
+	return info
+}
+
+// isEnabled returns whether an optional config switch is set and true.
+func isEnabled(b *bool) bool {
+	return b != nil && *b
 }
 
 // CreatePod accepts a Pod definition and stores it in memory.
-func (p *MockProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
-	log.Printf("receive CreatePod %q\n", pod.Name)
+// Creating a pod which already exists never changes its status, so a terminated pod stays terminated:
+// its definition is updated, left unchanged or the call fails, depending on the OnDuplicateCreate policy.
+// A pod whose UID differs has been re-created with the same name, and starts afresh.
+func (p *MockProvider) CreatePod(ctx context.Context, pod *v1.Pod) (err error) {
+	start := p.clock.Now()
+	ctx, span := startSpan(ctx, operationCreatePod, pod.Namespace, pod.Name)
+	defer func() {
+		endSpan(span, err)
+		p.auditor.audit(traceRecord{Operation: operationCreatePod, Pod: pod}, start, err)
+	}()
+
+	p.logger.Printf("receive CreatePod %q\n", pod.Name)
+	p.recorder.record(operationCreatePod, pod)
+
+	if err := p.beforeOperation(ctx, operationCreatePod); err != nil {
+		return err
+	}
 
 	key, err := buildKey(pod)
 	if err != nil {
 		return err
 	}
 
+	p.mu.Lock()
+	old, exist := p.pods[key]
+	if exist && old.UID != pod.UID {
+		// The pod has been re-created with the same name, so it starts afresh.
+		p.forgetPod(key)
+		exist = false
+	}
+	if exist {
+		switch p.config.OnDuplicateCreate {
+		case duplicateCreateIgnore:
+			p.mu.Unlock()
+			return nil
+		case duplicateCreateReject:
+			p.mu.Unlock()
+			return errors.NewAlreadyExists(v1.Resource("pods"), pod.Name)
+		}
+	}
+	if !exist {
+		if status := p.admitPod(key, pod); status != nil {
+			p.rejected[key] = status
+			p.pods[key] = pod
+			p.schedulePrune(key)
+			p.mu.Unlock()
+
+			p.logger.Printf("reject pod %q: %s\n", pod.Name, status.Message)
+			p.notifyPodStatus(pod, status)
+			return nil
+		}
+
+		podIP, err := p.ipam.allocate()
+		if err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.podIPs[key] = podIP
+		p.accountPod(key, pod)
+
+		now := p.clock.Now()
+		p.startTimes[key] = metav1.NewTime(now)
+
+		if delay := p.startupDelay.of(pod); delay > 0 {
+			startup := podStartup{accepted: metav1.NewTime(now), readyAt: now.Add(delay)}
+			p.starting[key] = startup
+			p.pods[key] = pod
+			p.mu.Unlock()
+
+			p.notifyPodStatus(pod, pendingPodStatus(pod, startup.accepted))
+			p.clock.AfterFunc(delay, func() { p.finishStartup(key, startup) })
+			return nil
+		}
+	}
 	p.pods[key] = pod
+	podIP := p.podIPs[key]
+	startTime := p.startTimes[key]
+	rejected := p.rejected[key] != nil
+	p.mu.Unlock()
+
+	if !exist && !rejected {
+		p.notifyPodStatus(pod, runningPodStatus(pod, podIP, startTime))
+	}
 
 	return nil
 }
 
-// UpdatePod accepts a Pod definition and updates its reference.
-func (p *MockProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
-	log.Printf("receive UpdatePod %q\n", pod.Name)
+// admitPod checks that the pod stored at key can run on the node: its spec must be valid, and it must fit the
+// quota of its namespace, the capacity, the exclusive CPUs and the host ports left by the other pods. It returns
+// the rejected status of the pod, or nil if the pod is admitted, in which case other pods may have been
+// preempted to make room for it if it is critical. p.mu must be held.
+func (p *MockProvider) admitPod(key string, pod *v1.Pod) *v1.PodStatus {
+	if status := p.validatePod(pod); status != nil {
+		return status
+	}
+	if status := p.checkQuota(pod); status != nil {
+		return status
+	}
+	if status := p.checkCapacity(pod); status != nil {
+		return status
+	}
+	if status := p.checkExclusiveCPUs(pod); status != nil {
+		return status
+	}
+	if hp, conflict := p.findHostPortConflict(key, pod); conflict {
+		return rejectedPodStatus(hostPortConflictReason, fmt.Sprintf("Pod was rejected: host port %s is already in use", hp))
+	}
+	p.preemptForCriticalPod(pod)
+	return nil
+}
+
+// UpdatePod accepts a Pod definition and updates its reference. The status of the pod is left unchanged.
+// An update changing what the pod holds on the node, e.g. the resources of its containers, is admitted again
+// against the other pods: the update is rejected with a Forbidden error if the pod would no longer fit, and
+// the pod keeps its previous definition. Updating a pod which does not exist fails with a NotFound error.
+func (p *MockProvider) UpdatePod(ctx context.Context, pod *v1.Pod) (err error) {
+	start := p.clock.Now()
+	ctx, span := startSpan(ctx, operationUpdatePod, pod.Namespace, pod.Name)
+	defer func() {
+		endSpan(span, err)
+		p.auditor.audit(traceRecord{Operation: operationUpdatePod, Pod: pod}, start, err)
+	}()
+
+	p.logger.Printf("receive UpdatePod %q\n", pod.Name)
+	p.recorder.record(operationUpdatePod, pod)
+
+	if err := p.beforeOperation(ctx, operationUpdatePod); err != nil {
+		return err
+	}
 
 	key, err := buildKey(pod)
 	if err != nil {
 		return err
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	old, ok := p.pods[key]
+	if !ok {
+		return errors.NewNotFound(v1.Resource("pods"), pod.Name)
+	}
+	if p.holdsResources(key) && admissionChanged(old, pod) {
+		// The pod is admitted without its previous definition, so that it is not accounted twice.
+		p.releasePod(key)
+		delete(p.pods, key)
+		status := p.admitPod(key, pod)
+		p.pods[key] = old
+		if status != nil {
+			p.accountPod(key, old)
+			p.logger.Printf("reject update of pod %q: %s\n", pod.Name, status.Message)
+			return errors.NewForbidden(v1.Resource("pods"), pod.Name, fmt.Errorf("%s", status.Message))
+		}
+		p.accountPod(key, pod)
+	}
 	p.pods[key] = pod
 
 	return nil
 }
 
 // DeletePod deletes the specified pod out of memory.
+// If graceful deletion is enabled, the pod is kept terminating until its deletion grace period has elapsed,
+// then it is removed and its final status is pushed to the notifier. DeletePod does not wait for it.
+// The final status of the pod is audited.
 func (p *MockProvider) DeletePod(ctx context.Context, pod *v1.Pod) (err error) {
-	log.Printf("receive DeletePod %q\n", pod.Name)
+	var final *v1.PodStatus
+	start := p.clock.Now()
+	ctx, span := startSpan(ctx, operationDeletePod, pod.Namespace, pod.Name)
+	defer func() {
+		endSpan(span, err)
+		p.auditor.audit(traceRecord{Operation: operationDeletePod, Pod: pod, Status: final}, start, err)
+	}()
+
+	p.logger.Printf("receive DeletePod %q\n", pod.Name)
+	p.recorder.record(operationDeletePod, pod)
+
+	if err := p.beforeOperation(ctx, operationDeletePod); err != nil {
+		return err
+	}
 
 	key, err := buildKey(pod)
 	if err != nil {
 		return err
 	}
 
-	delete(p.pods, key)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if grace := p.gracePeriod(key, pod); grace > 0 {
+		final = p.startDeletion(key, pod, grace)
+		return nil
+	}
+
+	final = p.finalPodStatus(key, metav1.NewTime(p.clock.Now()))
+
+	p.forgetPod(key)
 
 	return nil
 }
 
+// podDeletion is the graceful deletion of a pod, which is terminating until finishAt.
+type podDeletion struct {
+	requested metav1.Time
+	finishAt  time.Time
+}
+
+// gracePeriod returns the time the pod stored at key is kept terminating when it is deleted.
+// It is zero unless graceful deletion is enabled, and for pods whose containers are no longer running.
+// Deleting a pod which is already terminating with a grace period of zero forces its deletion.
+// p.mu must be held.
+func (p *MockProvider) gracePeriod(key string, pod *v1.Pod) time.Duration {
+	if !isEnabled(p.config.GracefulDeletion) || pod.DeletionGracePeriodSeconds == nil || *pod.DeletionGracePeriodSeconds <= 0 {
+		return 0
+	}
+	if _, ok := p.pods[key]; !ok || p.terminated[key] != nil || p.rejected[key] != nil {
+		return 0
+	}
+	return time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second
+}
+
+// startDeletion keeps the pod stored at key terminating for the grace period, and returns its final status.
+// The grace period starts at the first deletion request of the pod, later requests leave it unchanged.
+// The pod releases its resources right away: its pod IP can be allocated again, so it is no longer reported.
+// The definition of the pod is replaced with the deleted one, so that the notified pod carries its deletion timestamp.
+// p.mu must be held.
+func (p *MockProvider) startDeletion(key string, pod *v1.Pod, grace time.Duration) *v1.PodStatus {
+	deletion, ok := p.terminating[key]
+	if !ok {
+		now := p.clock.Now()
+		deletion = podDeletion{requested: metav1.NewTime(now), finishAt: now.Add(grace)}
+		p.terminating[key] = deletion
+		p.pods[key] = pod
+		if podIP, ok := p.podIPs[key]; ok {
+			p.ipam.release(podIP)
+			delete(p.podIPs, key)
+		}
+		p.releasePod(key)
+		p.clock.AfterFunc(grace, func() { p.finishDeletion(key, deletion) })
+	}
+	return p.finalPodStatus(key, metav1.NewTime(deletion.finishAt))
+}
+
+// finishDeletion removes a pod once its deletion grace period has elapsed, unless its deletion has been forced
+// meanwhile, and pushes its final status to the notifier.
+func (p *MockProvider) finishDeletion(key string, deletion podDeletion) {
+	p.mu.Lock()
+	if current, ok := p.terminating[key]; !ok || current != deletion {
+		p.mu.Unlock()
+		return
+	}
+	pod := p.pods[key]
+	final := p.finalPodStatus(key, metav1.NewTime(p.clock.Now()))
+	p.forgetPod(key)
+	p.mu.Unlock()
+
+	p.logger.Printf("finish deletion of pod %q\n", pod.Name)
+	p.notifyPodStatus(pod, final)
+}
+
+// forgetPod removes the pod stored at key and releases its pod IP, unless it was released when its deletion
+// started. p.mu must be held.
+func (p *MockProvider) forgetPod(key string) {
+	if podIP, ok := p.podIPs[key]; ok {
+		p.ipam.release(podIP)
+	}
+	p.releasePod(key)
+	delete(p.pods, key)
+	delete(p.terminated, key)
+	delete(p.terminating, key)
+	delete(p.rejected, key)
+	delete(p.starting, key)
+	delete(p.podIPs, key)
+	delete(p.startTimes, key)
+}
+
+// schedulePrune prunes the pod stored at key, which was just terminated or rejected, once the terminated pod
+// TTL has elapsed. p.mu must be held.
+func (p *MockProvider) schedulePrune(key string) {
+	if p.terminatedPodTTL <= 0 {
+		return
+	}
+	terminated, rejected := p.terminated[key], p.rejected[key]
+	p.clock.AfterFunc(p.terminatedPodTTL, func() { p.prunePod(key, terminated, rejected) })
+}
+
+// prunePod removes a terminated or rejected pod, unless it has been deleted, re-created or terminated again
+// meanwhile. Pods being deleted gracefully are left to finish their deletion.
+func (p *MockProvider) prunePod(key string, terminated *v1.ContainerStateTerminated, rejected *v1.PodStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, terminating := p.terminating[key]; terminating || p.terminated[key] != terminated || p.rejected[key] != rejected {
+		return
+	}
+	pod, ok := p.pods[key]
+	if !ok {
+		return
+	}
+
+	p.logger.Printf("prune terminated pod %q\n", pod.Name)
+	p.forgetPod(key)
+}
+
+// holdsResources returns whether the pod stored at key holds resources of the node: its host ports, its
+// share of the namespace quota and its exclusive CPUs. Pods release them once terminated, rejected or
+// terminating. p.mu must be held.
+func (p *MockProvider) holdsResources(key string) bool {
+	_, terminating := p.terminating[key]
+	return p.terminated[key] == nil && p.rejected[key] == nil && !terminating
+}
+
+// finalPodStatus returns the status of a pod being deleted, whose containers are killed at the given time unless
+// they are already terminated. It returns nil if the pod does not exist. p.mu must be held.
+func (p *MockProvider) finalPodStatus(key string, killedAt metav1.Time) *v1.PodStatus {
+	pod, ok := p.pods[key]
+	if !ok {
+		return nil
+	}
+	if rejected := p.rejected[key]; rejected != nil {
+		return rejected.DeepCopy()
+	}
+
+	terminated := p.terminated[key]
+	if terminated == nil {
+		terminated = newTermination(p.startTimes[key], 137, "Killed", "Pod was deleted", killedAt)
+	}
+	return terminatedPodStatus(pod, terminated, p.podIPs[key])
+}
+
 // GetPod returns a pod by name that is stored in memory.
 func (p *MockProvider) GetPod(ctx context.Context, namespace, name string) (pod *v1.Pod, err error) {
-	log.Printf("receive GetPod %q\n", name)
+	start := p.clock.Now()
+	defer func() {
+		p.auditor.audit(traceRecord{Operation: operationGetPod, Namespace: namespace, Name: name}, start, err)
+	}()
+
+	p.logger.Printf("receive GetPod %q\n", name)
+	return p.getPod(namespace, name)
+}
 
+// getPod returns a pod by name that is stored in memory.
+func (p *MockProvider) getPod(namespace, name string) (*v1.Pod, error) {
 	key, err := buildKeyFromNames(namespace, name)
 	if err != nil {
 		return nil, err
 	}
 
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if pod, ok := p.pods[key]; ok {
 		return pod, nil
 	}
@@ -156,7 +930,8 @@ func (p *MockProvider) GetPod(ctx context.Context, namespace, name string) (pod
 
 // GetContainerLogs retrieves the logs of a container by name from the provider.
 func (p *MockProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
-	log.Printf("receive GetContainerLogs %q\n", podName)
+	p.auditor.audit(traceRecord{Operation: operationGetContainerLogs, Namespace: namespace, Name: podName}, p.clock.Now(), nil)
+	p.logger.Printf("receive GetContainerLogs %q\n", podName)
 	return "", nil
 }
 
@@ -169,67 +944,103 @@ func (p *MockProvider) GetPodFullName(namespace string, pod string) string {
 // ExecInContainer executes a command in a container in the pod, copying data
 // between in/out/err and the container's stdin/stdout/stderr.
 func (p *MockProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
-	log.Printf("receive ExecInContainer %q\n", container)
+	p.auditor.audit(traceRecord{Operation: operationExecInContainer, Name: name}, p.clock.Now(), nil)
+	p.logger.Printf("receive ExecInContainer %q\n", container)
 	return nil
 }
 
-// GetPodStatus returns the status of a pod by name that is "running".
+// GetPodStatus returns the status of a pod by name that is "running", "pending" during its startup delay,
+// or "failed" if the pod has been terminated through the admin API, or rejected because of its spec, the quota of its
+// namespace or a host port conflict.
 // returns nil if a pod by that name is not found.
-func (p *MockProvider) GetPodStatus(ctx context.Context, namespace, name string) (*v1.PodStatus, error) {
-	log.Printf("receive GetPodStatus %q\n", name)
+func (p *MockProvider) GetPodStatus(ctx context.Context, namespace, name string) (status *v1.PodStatus, err error) {
+	start := p.clock.Now()
+	ctx, span := startSpan(ctx, operationGetPodStatus, namespace, name)
+	defer func() {
+		endSpan(span, err)
+		p.auditor.audit(traceRecord{Operation: operationGetPodStatus, Namespace: namespace, Name: name, Status: status}, start, err)
+	}()
 
-	now := metav1.NewTime(time.Now())
+	p.logger.Printf("receive GetPodStatus %q\n", name)
 
-	status := &v1.PodStatus{
-		Phase:     v1.PodRunning,
-		HostIP:    "1.2.3.4",
-		PodIP:     "5.6.7.8",
-		StartTime: &now,
-		Conditions: []v1.PodCondition{
-			{
-				Type:   v1.PodInitialized,
-				Status: v1.ConditionTrue,
-			},
-			{
-				Type:   v1.PodReady,
-				Status: v1.ConditionTrue,
-			},
-			{
-				Type:   v1.PodScheduled,
-				Status: v1.ConditionTrue,
-			},
-		},
+	if err := p.beforeOperation(ctx, operationGetPodStatus); err != nil {
+		return nil, err
+	}
+
+	pod, err := p.getPod(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return nil, nil
 	}
 
-	pod, err := p.GetPod(ctx, namespace, name)
+	key, err := buildKeyFromNames(namespace, name)
 	if err != nil {
-		return status, err
+		return nil, err
 	}
 
-	for _, container := range pod.Spec.Containers {
-		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
-			Name:         container.Name,
-			Image:        container.Image,
-			Ready:        true,
-			RestartCount: 0,
-			State: v1.ContainerState{
-				Running: &v1.ContainerStateRunning{
-					StartedAt: now,
-				},
-			},
-		})
+	return p.currentPodStatus(key, pod), nil
+}
+
+// currentPodStatus computes the current status of the pod stored at key.
+func (p *MockProvider) currentPodStatus(key string, pod *v1.Pod) *v1.PodStatus {
+	p.mu.Lock()
+	if rejected := p.rejected[key]; rejected != nil {
+		p.mu.Unlock()
+		return rejected.DeepCopy()
+	}
+	terminated := p.terminated[key]
+	if startup, ok := p.starting[key]; ok && terminated == nil {
+		if p.clock.Now().Before(startup.readyAt) {
+			p.mu.Unlock()
+			return pendingPodStatus(pod, startup.accepted)
+		}
+		delete(p.starting, key)
 	}
+	if terminated == nil && p.chaos.killPod() {
+		terminated = newTermination(p.startTimes[key], 137, "Killed", "Pod was killed by chaos injection", metav1.NewTime(p.clock.Now()))
+		p.terminated[key] = terminated
+		p.releasePod(key)
+		p.schedulePrune(key)
+	}
+	podIP := p.podIPs[key]
+	startTime := p.startTimes[key]
+	deletion, terminating := p.terminating[key]
+	p.mu.Unlock()
 
-	return status, nil
+	if terminated != nil {
+		return terminatedPodStatus(pod, terminated, podIP)
+	}
+	if terminating {
+		return terminatingPodStatus(pod, startTime, deletion.requested)
+	}
+
+	if p.chaos.corruptStatus() {
+		return &v1.PodStatus{Phase: v1.PodUnknown}
+	}
+
+	return runningPodStatus(pod, podIP, startTime)
 }
 
 // GetPods returns a list of all pods known to be "running".
+// The status of each pod is the one GetPodStatus would return, so that the list is a consistent snapshot.
 func (p *MockProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
-	log.Printf("receive GetPods\n")
+	p.auditor.audit(traceRecord{Operation: operationGetPods}, p.clock.Now(), nil)
+	p.logger.Printf("receive GetPods\n")
+
+	p.mu.RLock()
+	stored := make(map[string]*v1.Pod, len(p.pods))
+	for key, pod := range p.pods {
+		stored[key] = pod
+	}
+	p.mu.RUnlock()
 
 	var pods []*v1.Pod
 
-	for _, pod := range p.pods {
+	for key, pod := range stored {
+		pod = pod.DeepCopy()
+		pod.Status = *p.currentPodStatus(key, pod)
 		pods = append(pods, pod)
 	}
 
@@ -238,71 +1049,145 @@ func (p *MockProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 
 // Capacity returns a resource list containing the capacity limits.
 func (p *MockProvider) Capacity(ctx context.Context) v1.ResourceList {
-	return v1.ResourceList{
-		"cpu":    resource.MustParse(p.config.CPU),
-		"memory": resource.MustParse(p.config.Memory),
-		"pods":   resource.MustParse(p.config.Pods),
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.capacity()
+}
+
+// ShouldRenewNodeLease reports whether the node lease should be renewed, which is not the case while
+// the node is blackholed or the renewal is paused through the admin API.
+func (p *MockProvider) ShouldRenewNodeLease(ctx context.Context) bool {
+	if p.chaos.blackholed() {
+		return false
 	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return !p.leasePaused
+}
+
+// conditionTransition is the last transition of a node condition.
+type conditionTransition struct {
+	status v1.ConditionStatus
+	time   metav1.Time
+}
+
+// nodeConditionTypes are the types of the conditions reported by NodeConditions.
+var nodeConditionTypes = map[v1.NodeConditionType]bool{
+	v1.NodeReady:              true,
+	v1.NodeOutOfDisk:          true,
+	v1.NodeMemoryPressure:     true,
+	v1.NodeDiskPressure:       true,
+	v1.NodeNetworkUnavailable: true,
 }
 
 // NodeConditions returns a list of conditions (Ready, OutOfDisk, etc), for updates to the node status
 // within Kubernetes.
 func (p *MockProvider) NodeConditions(ctx context.Context) []v1.NodeCondition {
-	// TODO: Make this configurable
-	return []v1.NodeCondition{
+	now := metav1.NewTime(p.clock.Now())
+	conditions := []v1.NodeCondition{
 		{
 			Type:               "Ready",
 			Status:             v1.ConditionTrue,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
 			Reason:             "KubeletReady",
 			Message:            "kubelet is ready.",
 		},
 		{
 			Type:               "OutOfDisk",
 			Status:             v1.ConditionFalse,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
 			Reason:             "KubeletHasSufficientDisk",
 			Message:            "kubelet has sufficient disk space available",
 		},
 		{
 			Type:               "MemoryPressure",
 			Status:             v1.ConditionFalse,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
 			Reason:             "KubeletHasSufficientMemory",
 			Message:            "kubelet has sufficient memory available",
 		},
 		{
 			Type:               "DiskPressure",
 			Status:             v1.ConditionFalse,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
 			Reason:             "KubeletHasNoDiskPressure",
 			Message:            "kubelet has no disk pressure",
 		},
 		{
 			Type:               "NetworkUnavailable",
 			Status:             v1.ConditionFalse,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
 			Reason:             "RouteCreated",
 			Message:            "RouteController created a route",
 		},
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range conditions {
+		if o, ok := p.conditions[conditions[i].Type]; ok {
+			conditions[i].Status = o.Status
+			conditions[i].Reason = o.Reason
+			conditions[i].Message = o.Message
+		}
+	}
+
+	if p.chaos.maybeBlackhole() {
+		conditions[0].Status = v1.ConditionUnknown
+		conditions[0].Reason = "NodeStatusUnknown"
+		conditions[0].Message = "Kubelet stopped posting node status."
+	}
+
+	// Only bump the transition time of the conditions whose status changed since the last call.
+	for i := range conditions {
+		t, ok := p.transitions[conditions[i].Type]
+		if !ok || t.status != conditions[i].Status {
+			t = conditionTransition{status: conditions[i].Status, time: conditions[i].LastTransitionTime}
+			p.transitions[conditions[i].Type] = t
+		}
+		conditions[i].LastTransitionTime = t.time
+	}
+
+	return conditions
 }
 
 // NodeAddresses returns a list of addresses for the node status
 // within Kubernetes.
 func (p *MockProvider) NodeAddresses(ctx context.Context) []v1.NodeAddress {
-	return []v1.NodeAddress{
+	addresses := []v1.NodeAddress{
 		{
 			Type:    "InternalIP",
 			Address: p.internalIP,
 		},
 	}
+
+	for _, ip := range p.config.InternalIPs {
+		if ip != p.internalIP {
+			addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: ip})
+		}
+	}
+	for _, ip := range p.config.ExternalIPs {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: ip})
+	}
+	if p.config.Hostname != "" {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeHostName, Address: p.config.Hostname})
+	}
+
+	return addresses
+}
+
+// PodCIDR returns the range the IPs of the pods are allocated from.
+func (p *MockProvider) PodCIDR(ctx context.Context) string {
+	return p.config.PodCIDR
 }
 
 // NodeDaemonEndpoints returns NodeDaemonEndpoints for the node status
@@ -315,12 +1200,217 @@ func (p *MockProvider) NodeDaemonEndpoints(ctx context.Context) *v1.NodeDaemonEn
 	}
 }
 
+// NotifyPods registers the function called with the pods whose status changed.
+func (p *MockProvider) NotifyPods(ctx context.Context, notifier func(*v1.Pod)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.notifier = notifier
+}
+
+// notifyPodStatus pushes the new status of the pod to the registered notifier, if any.
+func (p *MockProvider) notifyPodStatus(pod *v1.Pod, status *v1.PodStatus) {
+	p.mu.RLock()
+	notifier := p.notifier
+	p.mu.RUnlock()
+
+	if notifier == nil {
+		return
+	}
+
+	pod = pod.DeepCopy()
+	pod.Status = *status
+	notifier(pod)
+}
+
 // OperatingSystem returns the operating system for this provider.
-// This is a noop to default to Linux for now.
+// It defaults to Linux unless another one is configured.
 func (p *MockProvider) OperatingSystem() string {
+	if p.config.NodeInfo.OperatingSystem != "" {
+		return p.config.NodeInfo.OperatingSystem
+	}
 	return providers.OperatingSystemLinux
 }
 
+// NodeInfo returns the system info of the node configured for this provider.
+func (p *MockProvider) NodeInfo(ctx context.Context) v1.NodeSystemInfo {
+	info := p.config.NodeInfo
+	return v1.NodeSystemInfo{
+		OperatingSystem:         p.OperatingSystem(),
+		Architecture:            info.Architecture,
+		KernelVersion:           info.KernelVersion,
+		OSImage:                 info.OSImage,
+		KubeletVersion:          info.KubeletVersion,
+		ContainerRuntimeVersion: info.ContainerRuntimeVersion,
+	}
+}
+
+// podStartup is the startup of a pod which is pending until readyAt.
+type podStartup struct {
+	accepted metav1.Time
+	readyAt  time.Time
+}
+
+// finishStartup reports a pod running once its startup delay has elapsed, unless it has been
+// deleted, terminated or started terminating meanwhile.
+func (p *MockProvider) finishStartup(key string, startup podStartup) {
+	p.mu.Lock()
+	if current, ok := p.starting[key]; !ok || current != startup {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.starting, key)
+	pod := p.pods[key]
+	podIP := p.podIPs[key]
+	startTime := p.startTimes[key]
+	terminated := p.terminated[key] != nil
+	_, terminating := p.terminating[key]
+	p.mu.Unlock()
+
+	if pod != nil && !terminated && !terminating {
+		p.notifyPodStatus(pod, runningPodStatus(pod, podIP, startTime))
+	}
+}
+
+// beforeOperation enforces the rate limit and injects the faults configured for a provider operation.
+func (p *MockProvider) beforeOperation(ctx context.Context, op string) error {
+	if err := p.rateLimiter.wait(ctx, op); err != nil {
+		return err
+	}
+	if err := p.chaos.disturb(ctx, op); err != nil {
+		return err
+	}
+	return p.checkInjectedFailure(op)
+}
+
+// newTermination builds the terminated state of the containers of a pod started at startedAt and killed at now.
+func newTermination(startedAt metav1.Time, exitCode int32, reason, message string, now metav1.Time) *v1.ContainerStateTerminated {
+	return &v1.ContainerStateTerminated{
+		ExitCode:   exitCode,
+		Reason:     reason,
+		Message:    message,
+		StartedAt:  startedAt,
+		FinishedAt: now,
+	}
+}
+
+// runningPodStatus builds the status of a pod whose containers are all running since startTime.
+func runningPodStatus(pod *v1.Pod, podIP string, startTime metav1.Time) *v1.PodStatus {
+	status := &v1.PodStatus{
+		Phase:      v1.PodRunning,
+		HostIP:     "1.2.3.4",
+		PodIP:      podIP,
+		StartTime:  &startTime,
+		Conditions: podConditions(pod, true, "", startTime),
+	}
+
+	for _, container := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
+			Name:         container.Name,
+			Image:        container.Image,
+			Ready:        true,
+			RestartCount: 0,
+			State: v1.ContainerState{
+				Running: &v1.ContainerStateRunning{
+					StartedAt: startTime,
+				},
+			},
+		})
+	}
+
+	return status
+}
+
+// terminatingPodStatus builds the status of a pod whose deletion was requested at requested, and whose
+// containers are still running until its grace period elapses. The pod is no longer ready, and its pod IP,
+// which was released, is no longer reported.
+func terminatingPodStatus(pod *v1.Pod, startTime, requested metav1.Time) *v1.PodStatus {
+	status := runningPodStatus(pod, "", startTime)
+	status.Conditions = podConditions(pod, false, containersNotReadyReason, requested)
+	for i := range status.ContainerStatuses {
+		status.ContainerStatuses[i].Ready = false
+	}
+	return status
+}
+
+// podConditions builds the conditions of a pod whose containers are all ready or all not ready,
+// in which case reason tells why. transition is the time the readiness last changed.
+func podConditions(pod *v1.Pod, ready bool, reason string, transition metav1.Time) []v1.PodCondition {
+	conditions := []v1.PodCondition{
+		{
+			Type:               v1.PodInitialized,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: pod.CreationTimestamp,
+		},
+		{
+			Type:               v1.PodReady,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: transition,
+		},
+		{
+			Type:               v1.ContainersReady,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: transition,
+		},
+		{
+			Type:               v1.PodScheduled,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: pod.CreationTimestamp,
+		},
+	}
+	if ready {
+		return conditions
+	}
+
+	var names []string
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	for i := range conditions {
+		if c := &conditions[i]; c.Type == v1.PodReady || c.Type == v1.ContainersReady {
+			c.Status = v1.ConditionFalse
+			c.Reason = reason
+			if reason == containersNotReadyReason {
+				c.Message = fmt.Sprintf("containers with unready status: %v", names)
+			}
+		}
+	}
+	return conditions
+}
+
+// terminatedPodStatus builds the status of a pod whose containers have all been terminated.
+// The pod succeeded if they exited with code 0, and failed otherwise.
+func terminatedPodStatus(pod *v1.Pod, terminated *v1.ContainerStateTerminated, podIP string) *v1.PodStatus {
+	phase := v1.PodFailed
+	if terminated.ExitCode == 0 {
+		phase = v1.PodSucceeded
+	}
+
+	status := &v1.PodStatus{
+		Phase:      phase,
+		Reason:     terminated.Reason,
+		Message:    terminated.Message,
+		HostIP:     "1.2.3.4",
+		PodIP:      podIP,
+		StartTime:  &terminated.StartedAt,
+		Conditions: podConditions(pod, false, podCompletedReason, terminated.FinishedAt),
+	}
+
+	for _, container := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
+			Name:         container.Name,
+			Image:        container.Image,
+			Ready:        false,
+			RestartCount: 0,
+			State: v1.ContainerState{
+				Terminated: terminated,
+			},
+		})
+	}
+
+	return status
+}
+
 func buildKeyFromNames(namespace string, name string) (string, error) {
 	return fmt.Sprintf("%s-%s", namespace, name), nil
 }
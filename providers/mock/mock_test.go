@@ -0,0 +1,689 @@
+package mock
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	f, err := ioutil.TempFile("", "mock-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := writeConfig(t, `{
+		"defaults": {"cpu": "4", "memory": "8Gi"},
+		"node-a": {"cpu": "2"}
+	}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPU != "2" {
+		t.Errorf("Got cpu %s, expected 2", config.CPU)
+	}
+	if config.Memory != "8Gi" {
+		t.Errorf("Got memory %s, expected 8Gi", config.Memory)
+	}
+	if config.Pods != defaultPodCapacity {
+		t.Errorf("Got pods %s, expected %s", config.Pods, defaultPodCapacity)
+	}
+
+	config, err = loadConfig(path, "node-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPU != "4" {
+		t.Errorf("Got cpu %s, expected 4", config.CPU)
+	}
+	if config.Memory != "8Gi" {
+		t.Errorf("Got memory %s, expected 8Gi", config.Memory)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	path := writeConfig(t, `{
+		"defaults": {"gracefulDeletion": true, "disableHostNetwork": true, "nodeInfo": {"operatingSystem": "Windows", "kubeletVersion": "v1.12.0"}},
+		"node-a": {"gracefulDeletion": false, "nodeInfo": {"kubeletVersion": "v1.13.0"}}
+	}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isEnabled(config.GracefulDeletion) {
+		t.Error("Expected graceful deletion to be turned off for node-a")
+	}
+	if !isEnabled(config.DisableHostNetwork) {
+		t.Error("Expected the host network to be disabled from the defaults")
+	}
+	if config.NodeInfo.OperatingSystem != "Windows" {
+		t.Errorf("Got operating system %s, expected Windows", config.NodeInfo.OperatingSystem)
+	}
+	if config.NodeInfo.KubeletVersion != "v1.13.0" {
+		t.Errorf("Got kubelet version %s, expected v1.13.0", config.NodeInfo.KubeletVersion)
+	}
+}
+
+func TestLoadConfigWithoutDefaults(t *testing.T) {
+	path := writeConfig(t, `{"node-a": {"memory": "1Gi"}}`)
+	defer os.Remove(path)
+
+	config, err := loadConfig(path, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPU != defaultCPUCapacity {
+		t.Errorf("Got cpu %s, expected %s", config.CPU, defaultCPUCapacity)
+	}
+	if config.Memory != "1Gi" {
+		t.Errorf("Got memory %s, expected 1Gi", config.Memory)
+	}
+
+	if _, err := loadConfig(path, "node-b"); err == nil {
+		t.Error("Expected an error for a node without config")
+	}
+}
+
+func TestNewMockProviderWithConfig(t *testing.T) {
+	p, err := NewMockProviderWithConfig(MockConfig{CPU: "4", HugePages: map[string]string{"2Mi": "1Gi"}}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacity := p.Capacity(context.Background())
+	if cpu := capacity[v1.ResourceCPU]; cpu.String() != "4" {
+		t.Errorf("Got cpu %s, expected 4", cpu.String())
+	}
+	if memory := capacity[v1.ResourceMemory]; memory.String() != defaultMemoryCapacity {
+		t.Errorf("Got memory %s, expected %s", memory.String(), defaultMemoryCapacity)
+	}
+	if hugePages := capacity["hugepages-2Mi"]; hugePages.String() != "1Gi" {
+		t.Errorf("Got hugepages-2Mi %s, expected 1Gi", hugePages.String())
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{Pods: "many"}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an invalid pods value")
+	}
+	if _, err := NewMockProviderWithConfig(MockConfig{HugePages: map[string]string{"huge": "1Gi"}}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an invalid huge page size")
+	}
+}
+
+func TestNodeAddresses(t *testing.T) {
+	path := writeConfig(t, `{"vk": {"internalIPs": ["10.0.0.1", "10.0.0.2"], "externalIPs": ["203.0.113.1"], "hostname": "vk.example.com"}}`)
+	defer os.Remove(path)
+
+	p, err := NewMockProvider(path, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeInternalIP, Address: "10.0.0.1"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.2"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.1"},
+		{Type: v1.NodeHostName, Address: "vk.example.com"},
+	}
+	addresses := p.NodeAddresses(context.Background())
+	if len(addresses) != len(expected) {
+		t.Fatalf("Got %d addresses, expected %d", len(addresses), len(expected))
+	}
+	for i := range expected {
+		if addresses[i] != expected[i] {
+			t.Errorf("Got address %v, expected %v", addresses[i], expected[i])
+		}
+	}
+
+	path = writeConfig(t, `{"vk": {"externalIPs": ["not-an-ip"]}}`)
+	defer os.Remove(path)
+	if _, err := loadConfig(path, "vk"); err == nil {
+		t.Error("Expected an error for an invalid external IP")
+	}
+}
+
+func TestNodeInfo(t *testing.T) {
+	path := writeConfig(t, `{"defaults": {"nodeInfo": {"operatingSystem": "Windows", "kubeletVersion": "v1.12.0"}}}`)
+	defer os.Remove(path)
+
+	p, err := NewMockProvider(path, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os := p.OperatingSystem(); os != "Windows" {
+		t.Errorf("Got operating system %s, expected Windows", os)
+	}
+	info := p.NodeInfo(context.Background())
+	if info.OperatingSystem != "Windows" || info.KubeletVersion != "v1.12.0" {
+		t.Errorf("Got node info %+v, expected Windows and v1.12.0", info)
+	}
+
+	path = writeConfig(t, `{"vk": {"nodeInfo": {"operatingSystem": "Plan9"}}}`)
+	defer os.Remove(path)
+	if _, err := loadConfig(path, "vk"); err == nil {
+		t.Error("Expected an error for an invalid operating system")
+	}
+}
+
+func TestGetPodStatusRunning(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodRunning)
+	}
+
+	status, err = p.GetPodStatus(ctx, "default", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != nil {
+		t.Errorf("Got status %v, expected nil for an unknown pod", status)
+	}
+}
+
+func TestPodIPs(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	ips := make(map[string]bool)
+	for _, name := range []string{"foo", "bar"} {
+		if err := p.CreatePod(ctx, makePod("default", name)); err != nil {
+			t.Fatal(err)
+		}
+		status, err := p.GetPodStatus(ctx, "default", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ips[status.PodIP] {
+			t.Errorf("Got pod IP %s twice", status.PodIP)
+		}
+		ips[status.PodIP] = true
+	}
+
+	if err := p.DeletePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.ipam.used); n != 1 {
+		t.Errorf("Got %d pod IPs in use, expected 1", n)
+	}
+	if cidr := p.PodCIDR(ctx); cidr != defaultPodCIDR {
+		t.Errorf("Got pod CIDR %s, expected %s", cidr, defaultPodCIDR)
+	}
+}
+
+func TestNotifyPods(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	var notified []*v1.Pod
+	p.NotifyPods(ctx, func(pod *v1.Pod) {
+		notified = append(notified, pod)
+	})
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 {
+		t.Fatalf("Got %d notifications, expected 1", len(notified))
+	}
+	if notified[0].Status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s", notified[0].Status.Phase, v1.PodRunning)
+	}
+
+	if code := doAdminRequest(t, p, "POST", "/pods/default/foo/terminate", ""); code != 200 {
+		t.Fatalf("Got status %d, expected 200", code)
+	}
+	if len(notified) != 2 {
+		t.Fatalf("Got %d notifications, expected 2", len(notified))
+	}
+	if notified[1].Status.Phase != v1.PodFailed {
+		t.Errorf("Got phase %s, expected %s", notified[1].Status.Phase, v1.PodFailed)
+	}
+}
+
+func TestGracefulDeletion(t *testing.T) {
+	clock := newFakeClock()
+	p, err := NewMockProviderWithConfig(MockConfig{GracefulDeletion: boolPtr(true)}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, name := range []string{"foo", "bar"} {
+		if err := p.CreatePod(ctx, makePod("default", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notified := make(chan *v1.Pod, 2)
+	p.NotifyPods(context.Background(), func(pod *v1.Pod) { notified <- pod })
+
+	grace := int64(30)
+	for _, name := range []string{"foo", "bar"} {
+		pod := makePod("default", name)
+		pod.DeletionTimestamp = &metav1.Time{Time: clock.Now()}
+		pod.DeletionGracePeriodSeconds = &grace
+		if err := p.DeletePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock.Advance(29 * time.Second)
+	for _, name := range []string{"foo", "bar"} {
+		if got, _ := p.GetPod(ctx, "default", name); got == nil {
+			t.Errorf("Expected %s to be kept during its grace period", name)
+		}
+	}
+
+	// A deletion with no grace period forces the deletion of a terminating pod.
+	force := int64(0)
+	bar := makePod("default", "bar")
+	bar.DeletionGracePeriodSeconds = &force
+	if err := p.DeletePod(ctx, bar); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.GetPod(ctx, "default", "bar"); got != nil {
+		t.Error("Expected bar to be deleted")
+	}
+
+	clock.Advance(time.Second)
+	if got, _ := p.GetPod(ctx, "default", "foo"); got != nil {
+		t.Error("Expected foo to be deleted once its grace period elapsed")
+	}
+	select {
+	case pod := <-notified:
+		if pod.Name != "foo" || pod.DeletionTimestamp == nil {
+			t.Errorf("Got notified pod %s (deletion timestamp %v), expected foo being deleted", pod.Name, pod.DeletionTimestamp)
+		}
+		if pod.Status.Phase != v1.PodFailed {
+			t.Errorf("Got phase %s, expected %s", pod.Status.Phase, v1.PodFailed)
+		}
+	default:
+		t.Error("Expected the deleted pod to be notified")
+	}
+	select {
+	case pod := <-notified:
+		t.Errorf("Got unexpected notification of %s", pod.Name)
+	default:
+	}
+}
+
+func TestTerminatingPodStatus(t *testing.T) {
+	clock := newFakeClock()
+	p, err := NewMockProviderWithConfig(MockConfig{GracefulDeletion: boolPtr(true)}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	grace := int64(30)
+	foo := makePod("default", "foo")
+	foo.DeletionTimestamp = &metav1.Time{Time: clock.Now()}
+	foo.DeletionGracePeriodSeconds = &grace
+	if err := p.DeletePod(ctx, foo); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s while the pod terminates", status.Phase, v1.PodRunning)
+	}
+	if status.PodIP != "" {
+		t.Errorf("Got pod IP %s, expected the released pod IP not to be reported", status.PodIP)
+	}
+	for _, c := range status.Conditions {
+		if (c.Type == v1.PodReady || c.Type == v1.ContainersReady) && c.Status != v1.ConditionFalse {
+			t.Errorf("Got condition %s %s, expected %s while the pod terminates", c.Type, c.Status, v1.ConditionFalse)
+		}
+	}
+	for _, cs := range status.ContainerStatuses {
+		if cs.Ready {
+			t.Errorf("Expected container %s not to be ready while the pod terminates", cs.Name)
+		}
+	}
+}
+
+func TestGracefulDeletionReleasesResources(t *testing.T) {
+	clock := newFakeClock()
+	p, err := NewMockProviderWithConfig(MockConfig{GracefulDeletion: boolPtr(true)}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := p.CreatePod(ctx, makePodWithHostPort("foo", "", 8080)); err != nil {
+		t.Fatal(err)
+	}
+
+	grace := int64(30)
+	foo := makePodWithHostPort("foo", "", 8080)
+	foo.DeletionTimestamp = &metav1.Time{Time: clock.Now()}
+	foo.DeletionGracePeriodSeconds = &grace
+	if err := p.DeletePod(ctx, foo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreatePod(ctx, makePodWithHostPort("bar", "", 8080)); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := p.GetPodStatus(ctx, "default", "bar"); err != nil {
+		t.Fatal(err)
+	} else if status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s once the host port of the terminating pod is released", status.Phase, v1.PodRunning)
+	}
+
+	p.mu.Lock()
+	used, pods := len(p.ipam.used), p.usageByNamespace()["default"].Pods
+	p.mu.Unlock()
+	if used != 1 || pods != 1 {
+		t.Errorf("Got %d pod IPs and %d pods in use, expected only those of bar", used, pods)
+	}
+
+	clock.Advance(time.Duration(grace) * time.Second)
+	p.mu.Lock()
+	used = len(p.ipam.used)
+	p.mu.Unlock()
+	if used != 1 {
+		t.Errorf("Got %d pod IPs in use, expected the IP of bar to stay allocated", used)
+	}
+}
+
+func TestTerminatedPodTTL(t *testing.T) {
+	clock := newFakeClock()
+	p, err := NewMockProviderWithConfig(MockConfig{TerminatedPodTTL: "1m"}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	noContainers := makePod("default", "bar")
+	noContainers.Spec.Containers = nil
+	for _, pod := range []*v1.Pod{makePod("default", "foo"), noContainers} {
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code := doAdminRequest(t, p, "POST", "/pods/default/foo/terminate", ""); code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", code, http.StatusOK)
+	}
+
+	clock.Advance(59 * time.Second)
+	for _, name := range []string{"foo", "bar"} {
+		if got, _ := p.GetPod(ctx, "default", name); got == nil {
+			t.Errorf("Expected %s to be kept until its TTL elapses", name)
+		}
+	}
+
+	clock.Advance(time.Second)
+	for _, name := range []string{"foo", "bar"} {
+		if got, _ := p.GetPod(ctx, "default", name); got != nil {
+			t.Errorf("Expected %s to be pruned once its TTL elapsed", name)
+		}
+	}
+	p.mu.Lock()
+	used := len(p.ipam.used)
+	p.mu.Unlock()
+	if used != 0 {
+		t.Errorf("Got %d pod IPs in use, expected the IP of the pruned pod to be released", used)
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{TerminatedPodTTL: "never"}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an invalid terminated pod TTL")
+	}
+}
+
+func TestGetPodsStatus(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	for _, pod := range []*v1.Pod{
+		makePodWithHostPort("foo", "", 8080),
+		makePodWithHostPort("bar", "", 8080),
+	} {
+		pod.Status.Phase = v1.PodPending
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pods, err := p.GetPods(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("Got %d pods, expected 2", len(pods))
+	}
+	for _, pod := range pods {
+		expected, err := p.GetPodStatus(ctx, pod.Namespace, pod.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pod.Status.Phase != expected.Phase {
+			t.Errorf("Got phase %s for %s, expected %s", pod.Status.Phase, pod.Name, expected.Phase)
+		}
+	}
+
+	stored, err := p.GetPod(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Phase != v1.PodPending {
+		t.Errorf("Got phase %s for the stored pod, expected it to be left unchanged", stored.Status.Phase)
+	}
+}
+
+func TestPodConditions(t *testing.T) {
+	pod := makePod("default", "foo")
+	now := metav1.Now()
+
+	for _, c := range []struct {
+		status   *v1.PodStatus
+		expected v1.ConditionStatus
+		reason   string
+	}{
+		{pendingPodStatus(pod, now), v1.ConditionFalse, containersNotReadyReason},
+		{runningPodStatus(pod, "10.244.0.1", now), v1.ConditionTrue, ""},
+		{terminatedPodStatus(pod, newTermination(now, 1, "Error", "", now), "10.244.0.1"), v1.ConditionFalse, podCompletedReason},
+	} {
+		for _, cond := range c.status.Conditions {
+			if cond.Type != v1.PodReady && cond.Type != v1.ContainersReady {
+				continue
+			}
+			if cond.Status != c.expected || cond.Reason != c.reason {
+				t.Errorf("Got %s condition %s (%s) for a %s pod, expected %s (%s)", cond.Type, cond.Status, cond.Reason, c.status.Phase, c.expected, c.reason)
+			}
+		}
+		if len(c.status.Conditions) != 4 {
+			t.Errorf("Got %d conditions for a %s pod, expected 4", len(c.status.Conditions), c.status.Phase)
+		}
+	}
+}
+
+func TestPodStartTime(t *testing.T) {
+	clock := newFakeClock()
+	p, err := NewMockProviderWithConfig(MockConfig{}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	pod := makePod("default", "foo")
+	pod.UID = "1"
+	if err := p.CreatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	created := clock.Now()
+	clock.Advance(time.Minute)
+
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.StartTime.Time.Equal(created) {
+		t.Errorf("Got start time %v, expected %v", status.StartTime.Time, created)
+	}
+
+	p.mu.Lock()
+	p.terminated["default-foo"] = newTermination(*status.StartTime, 0, "Completed", "", metav1.NewTime(clock.Now()))
+	p.mu.Unlock()
+	if err := p.CreatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = p.GetPodStatus(ctx, "default", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodSucceeded {
+		t.Errorf("Got phase %s, expected the terminated pod to stay terminated", status.Phase)
+	}
+
+	recreated := makePod("default", "foo")
+	recreated.UID = "2"
+	if err := p.CreatePod(ctx, recreated); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = p.GetPodStatus(ctx, "default", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodRunning || !status.StartTime.Time.Equal(clock.Now()) {
+		t.Errorf("Got phase %s and start time %v, expected the re-created pod to run since %v", status.Phase, status.StartTime.Time, clock.Now())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+
+	p, err := NewMockProviderWithConfig(MockConfig{RateLimit: &RateLimitConfig{QPS: 1, Burst: 2, Reject: true}}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetPodStatus(ctx, "default", "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.GetPodStatus(ctx, "default", "foo"); !errors.IsTooManyRequests(err) {
+		t.Errorf("Got error %v, expected the call exceeding the rate limit to be throttled", err)
+	} else if retryAfter, ok := errors.SuggestsClientDelay(err); !ok || retryAfter != 1 {
+		t.Errorf("Got retry after %d seconds, expected 1", retryAfter)
+	}
+	clock.Advance(time.Second)
+	if _, err := p.GetPodStatus(ctx, "default", "foo"); err != nil {
+		t.Errorf("Got error %v, expected the call to be allowed after a second", err)
+	}
+
+	if p, err = NewMockProviderWithConfig(MockConfig{RateLimit: &RateLimitConfig{QPS: 1}}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetPodStatus(ctx, "default", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := p.GetPodStatus(ctx, "default", "foo")
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Got error %v, expected the call to be delayed", err)
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{RateLimit: &RateLimitConfig{QPS: -1}}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for a negative qps")
+	}
+}
+
+func TestOnDuplicateCreate(t *testing.T) {
+	ctx := context.Background()
+
+	for policy, expectedImage := range map[string]string{
+		duplicateCreateUpdate: "nginx",
+		duplicateCreateIgnore: "busybox",
+		duplicateCreateReject: "busybox",
+	} {
+		p, err := NewMockProviderWithConfig(MockConfig{OnDuplicateCreate: policy}, "vk", "Linux", "10.0.0.1", 10250)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+			t.Fatal(err)
+		}
+		before, err := p.GetPodStatus(ctx, "default", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		duplicate := makePod("default", "foo")
+		duplicate.Spec.Containers[0].Image = "nginx"
+		err = p.CreatePod(ctx, duplicate)
+		if policy == duplicateCreateReject {
+			if !errors.IsAlreadyExists(err) {
+				t.Errorf("Got error %v with policy %s, expected AlreadyExists", err, policy)
+			}
+		} else if err != nil {
+			t.Errorf("Got error %v with policy %s, expected none", err, policy)
+		}
+
+		pod, err := p.GetPod(ctx, "default", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if image := pod.Spec.Containers[0].Image; image != expectedImage {
+			t.Errorf("Got image %s with policy %s, expected %s", image, policy, expectedImage)
+		}
+		after, err := p.GetPodStatus(ctx, "default", "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !after.StartTime.Equal(before.StartTime) || after.PodIP != before.PodIP {
+			t.Errorf("Got status %+v with policy %s, expected it to be left unchanged", after, policy)
+		}
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{OnDuplicateCreate: "merge"}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
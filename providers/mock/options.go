@@ -0,0 +1,26 @@
+package mock
+
+// MockProviderOption configures an optional parameter of a MockProvider.
+type MockProviderOption func(*MockProvider)
+
+// WithClock makes the provider use the given clock instead of the system time.
+func WithClock(clock Clock) MockProviderOption {
+	return func(p *MockProvider) {
+		p.clock = clock
+	}
+}
+
+// WithDeterministicFast turns off the perturbations of the simulation, as the DeterministicFast config does.
+func WithDeterministicFast() MockProviderOption {
+	return func(p *MockProvider) {
+		enabled := true
+		p.config.DeterministicFast = &enabled
+	}
+}
+
+// WithLogger makes the provider log to the given logger instead of the standard logger.
+func WithLogger(logger Logger) MockProviderOption {
+	return func(p *MockProvider) {
+		p.logger = logger
+	}
+}
@@ -0,0 +1,30 @@
+package mock
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// parseOverheads parses the runtime class overheads of the config, which are keyed by runtime class
+// and then by resource name.
+func parseOverheads(config map[string]map[string]string) (map[string]v1.ResourceList, error) {
+	overheads := make(map[string]v1.ResourceList, len(config))
+	for runtimeClass, values := range config {
+		overhead, err := parseResourceList(values)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid overhead of runtime class %s: %v", runtimeClass, err)
+		}
+		overheads[runtimeClass] = overhead
+	}
+	return overheads, nil
+}
+
+// podOverhead returns the resources the sandbox of the pod takes on top of its containers,
+// which is the overhead of its runtime class.
+func (p *MockProvider) podOverhead(pod *v1.Pod) v1.ResourceList {
+	if pod.Spec.RuntimeClassName == nil {
+		return nil
+	}
+	return p.overheads[*pod.Spec.RuntimeClassName]
+}
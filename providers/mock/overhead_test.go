@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRuntimeClassOverhead(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewMockProviderWithConfig(MockConfig{
+		RuntimeClassOverheads: map[string]map[string]string{"kata": {"cpu": "250m", "memory": "160Mi"}},
+	}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kata := "kata"
+	for _, name := range []string{"sandboxed", "unknown", "default"} {
+		pod := makePod("default", name)
+		pod.Spec.Containers[0].Resources = v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+			Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+		}
+		switch name {
+		case "sandboxed":
+			pod.Spec.RuntimeClassName = &kata
+		case "unknown":
+			unknown := "gvisor"
+			pod.Spec.RuntimeClassName = &unknown
+		}
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.mu.RLock()
+	u := p.usageByNamespace()["default"]
+	p.mu.RUnlock()
+	for _, c := range []struct {
+		list     v1.ResourceList
+		name     v1.ResourceName
+		expected string
+	}{
+		{u.Requests, v1.ResourceCPU, "1750m"},
+		{u.Requests, v1.ResourceMemory, "160Mi"},
+		{u.Limits, v1.ResourceCPU, "3250m"},
+	} {
+		if q := c.list[c.name]; q.String() != c.expected {
+			t.Errorf("Got %s %s, expected %s", c.name, q.String(), c.expected)
+		}
+	}
+	if _, ok := u.Limits[v1.ResourceMemory]; ok {
+		t.Error("Expected no memory limit")
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{
+		RuntimeClassOverheads: map[string]map[string]string{"kata": {"cpu": "-1"}},
+	}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for a negative overhead")
+	}
+}
@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// parseQuotas parses the namespace quotas of the config, which are keyed by namespace and then by resource name.
+func parseQuotas(config map[string]map[string]string) (map[string]v1.ResourceList, error) {
+	quotas := make(map[string]v1.ResourceList, len(config))
+	for namespace, hard := range config {
+		quota := make(v1.ResourceList, len(hard))
+		for name, value := range hard {
+			q, err := resource.ParseQuantity(value)
+			if err != nil || q.Sign() < 0 {
+				return nil, fmt.Errorf("Invalid %s quota %v of namespace %s", name, value, namespace)
+			}
+			quota[v1.ResourceName(name)] = q
+		}
+		quotas[namespace] = quota
+	}
+	return quotas, nil
+}
+
+// checkQuota returns the rejected status of the pod if it would exceed the quota of its namespace, or nil.
+// Only the resources the pod requests are checked, like the ResourceQuota admission plugin does.
+// p.mu must be held.
+func (p *MockProvider) checkQuota(pod *v1.Pod) *v1.PodStatus {
+	hard, ok := p.quotas[pod.Namespace]
+	if !ok {
+		return nil
+	}
+
+	requested := newNamespaceUsage()
+	requested.add(pod, p.resourceDefaults, p.podOverhead(pod))
+	used := newNamespaceUsage()
+	if u, ok := p.usage[pod.Namespace]; ok {
+		used = u
+	}
+	requestedUsage, usedUsage := requested.quotaUsage(), used.quotaUsage()
+
+	var exceeded []string
+	for name, limit := range hard {
+		r, ok := requestedUsage[name]
+		if !ok || r.IsZero() {
+			continue
+		}
+		total := usedUsage[name]
+		total.Add(r)
+		if total.Cmp(limit) > 0 {
+			u := usedUsage[name]
+			exceeded = append(exceeded, fmt.Sprintf("%s: requested %s, used %s, limited %s", name, r.String(), u.String(), limit.String()))
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	sort.Strings(exceeded)
+	return rejectedPodStatus(providers.PodReasonExceededQuota, fmt.Sprintf("Pod was rejected: exceeded quota of namespace %s: %s", pod.Namespace, strings.Join(exceeded, "; ")))
+}
@@ -0,0 +1,80 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNamespaceQuota(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewMockProviderWithConfig(MockConfig{
+		NamespaceQuotas: map[string]map[string]string{
+			"team-a": {"requests.cpu": "1", "pods": "3"},
+		},
+	}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	makeCPUPod := func(namespace, name, cpu string) *v1.Pod {
+		pod := makePod(namespace, name)
+		if cpu != "" {
+			pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}
+		}
+		return pod
+	}
+
+	for _, c := range []struct {
+		pod   *v1.Pod
+		phase v1.PodPhase
+	}{
+		{makeCPUPod("team-a", "foo", "600m"), v1.PodRunning},
+		{makeCPUPod("team-a", "bar", "600m"), v1.PodFailed},
+		{makeCPUPod("team-a", "baz", "400m"), v1.PodRunning},
+		{makeCPUPod("team-a", "qux", ""), v1.PodRunning},
+		{makeCPUPod("team-a", "quux", ""), v1.PodFailed},
+		{makeCPUPod("team-b", "foo", "2"), v1.PodRunning},
+	} {
+		if err := p.CreatePod(ctx, c.pod); err != nil {
+			t.Fatal(err)
+		}
+		status, err := p.GetPodStatus(ctx, c.pod.Namespace, c.pod.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase != c.phase {
+			t.Errorf("Got phase %s for %s/%s, expected %s", status.Phase, c.pod.Namespace, c.pod.Name, c.phase)
+		}
+		if c.phase == v1.PodFailed && status.Reason != providers.PodReasonExceededQuota {
+			t.Errorf("Got reason %s for %s/%s, expected %s", status.Reason, c.pod.Namespace, c.pod.Name, providers.PodReasonExceededQuota)
+		}
+	}
+
+	// Resizing a pod is admitted against the usage of the other pods of its namespace.
+	if err := p.UpdatePod(ctx, makeCPUPod("team-a", "baz", "300m")); err != nil {
+		t.Errorf("Unexpected error shrinking a pod: %v", err)
+	}
+	if err := p.UpdatePod(ctx, makeCPUPod("team-a", "baz", "500m")); !errors.IsForbidden(err) {
+		t.Errorf("Got error %v, expected Forbidden for a pod exceeding the quota", err)
+	}
+	if pod, _ := p.GetPod(ctx, "team-a", "baz"); pod.Spec.Containers[0].Resources.Requests.Cpu().String() != "300m" {
+		t.Errorf("Got cpu request %s, expected the rejected update to leave 300m", pod.Spec.Containers[0].Resources.Requests.Cpu())
+	}
+	if err := p.UpdatePod(ctx, makeCPUPod("team-a", "baz", "400m")); err != nil {
+		t.Errorf("Unexpected error growing a pod within the quota: %v", err)
+	}
+	if err := p.UpdatePod(ctx, makeCPUPod("team-a", "missing", "")); !errors.IsNotFound(err) {
+		t.Errorf("Got error %v, expected NotFound for an unknown pod", err)
+	}
+
+	if _, err := NewMockProviderWithConfig(MockConfig{
+		NamespaceQuotas: map[string]map[string]string{"team-a": {"pods": "many"}},
+	}, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Error("Expected an error for an invalid quota")
+	}
+}
@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// RateLimitConfig limits the rate of the CreatePod, UpdatePod, DeletePod and GetPodStatus calls,
+// like the APIs of cloud providers do.
+type RateLimitConfig struct {
+	// QPS is the number of calls allowed per second. Calls are not limited if it is zero.
+	QPS float64 `json:"qps,omitempty"`
+
+	// Burst is the number of calls allowed at once. It defaults to 1.
+	Burst int `json:"burst,omitempty"`
+
+	// Reject makes calls exceeding the limit fail with a TooManyRequests error instead of being delayed.
+	Reject bool `json:"reject,omitempty"`
+}
+
+// rateLimiter enforces a RateLimitConfig.
+// A nil *rateLimiter never limits any call.
+type rateLimiter struct {
+	limiter *rate.Limiter
+	reject  bool
+	clock   Clock
+}
+
+func newRateLimiter(config *RateLimitConfig, clock Clock) (*rateLimiter, error) {
+	if config == nil || config.QPS == 0 {
+		return nil, nil
+	}
+	if config.QPS < 0 {
+		return nil, fmt.Errorf("Invalid rate limit qps %v", config.QPS)
+	}
+	if config.Burst < 0 {
+		return nil, fmt.Errorf("Invalid rate limit burst %v", config.Burst)
+	}
+
+	burst := config.Burst
+	if burst == 0 {
+		burst = 1
+	}
+	return &rateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(config.QPS), burst),
+		reject:  config.Reject,
+		clock:   clock,
+	}, nil
+}
+
+// wait delays the operation until the rate limit allows it, or fails it with a TooManyRequests error telling
+// when to retry if calls exceeding the limit are rejected.
+func (l *rateLimiter) wait(ctx context.Context, op string) error {
+	if l == nil {
+		return nil
+	}
+
+	now := l.clock.Now()
+	r := l.limiter.ReserveN(now, 1)
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	if l.reject {
+		r.CancelAt(now)
+		retryAfter := int(math.Ceil(delay.Seconds()))
+		return errors.NewTooManyRequests(fmt.Sprintf("%s throttled: rate limit of %v calls per second exceeded", op, l.limiter.Limit()), retryAfter)
+	}
+
+	select {
+	case <-l.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		r.CancelAt(l.clock.Now())
+		return ctx.Err()
+	}
+}
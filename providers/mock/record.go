@@ -0,0 +1,196 @@
+package mock
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// traceRecord is a provider operation recorded in a trace file or an audit file.
+// Both contain one JSON encoded record per line, so that audit files can be replayed.
+type traceRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+
+	// Arguments of the operation.
+	Namespace string  `json:"namespace,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Pod       *v1.Pod `json:"pod,omitempty"`
+
+	// Results of the operation, which are only audited.
+	Duration time.Duration `json:"duration,omitempty"`
+	Status   *v1.PodStatus `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// recorder appends the operations received by the provider to a trace file or an audit file.
+// A nil *recorder doesn't record anything.
+type recorder struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	enc    *json.Encoder
+	clock  Clock
+	logger Logger
+}
+
+func newRecorder(path string, clock Clock, logger Logger) (*recorder, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", path, err)
+	}
+	return &recorder{path: path, f: f, enc: json.NewEncoder(f), clock: clock, logger: logger}, nil
+}
+
+// record appends an operation on the pod to the trace file.
+func (r *recorder) record(op string, pod *v1.Pod) {
+	if r == nil {
+		return
+	}
+	r.write(traceRecord{Time: r.clock.Now(), Operation: op, Pod: pod})
+}
+
+// audit appends a completed operation, started at start, and its error to the audit file.
+func (r *recorder) audit(rec traceRecord, start time.Time, err error) {
+	if r == nil {
+		return
+	}
+
+	rec.Time = start
+	rec.Duration = r.clock.Now().Sub(start)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	r.write(rec)
+}
+
+func (r *recorder) write(rec traceRecord) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return
+	}
+	if err := r.enc.Encode(rec); err != nil {
+		r.logger.Printf("error recording %s: %v\n", rec.Operation, err)
+	}
+}
+
+// close closes the file. Operations are no longer recorded once it is closed.
+func (r *recorder) close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	if err != nil {
+		return fmt.Errorf("error closing %s: %v", r.path, err)
+	}
+	return nil
+}
+
+// replay applies the pod operations of a trace to the provider.
+// The intervals between operations are preserved, divided by speed.
+func (p *MockProvider) replay(ctx context.Context, r io.Reader, speed float64) error {
+	var (
+		start   = p.clock.Now()
+		origin  time.Time
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		var rec traceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("error decoding trace record: %v", err)
+		}
+		if rec.Pod == nil {
+			continue
+		}
+		if origin.IsZero() {
+			origin = rec.Time
+		}
+
+		at := start.Add(time.Duration(float64(rec.Time.Sub(origin)) / speed))
+		select {
+		case <-p.clock.After(at.Sub(p.clock.Now())):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		var err error
+		switch rec.Operation {
+		case operationCreatePod:
+			err = p.CreatePod(ctx, rec.Pod)
+		case operationUpdatePod:
+			err = p.UpdatePod(ctx, rec.Pod)
+		case operationDeletePod:
+			err = p.DeletePod(ctx, rec.Pod)
+		default:
+			continue
+		}
+		if err != nil {
+			p.logger.Printf("error replaying %s %q: %v\n", rec.Operation, rec.Pod.Name, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// samePath returns whether two non-empty paths name the same file.
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// startReplay replays the trace file in the background.
+func (p *MockProvider) startReplay(path string, speed float64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening replay file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stopReplay = cancel
+	p.replayDone = make(chan struct{})
+
+	go func() {
+		defer close(p.replayDone)
+		defer f.Close()
+		if err := p.replay(ctx, f, speed); err != nil && err != context.Canceled {
+			p.logger.Printf("error replaying trace: %v\n", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package mock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	f, err := ioutil.TempFile("", "mock-trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	p := newTestProvider(t)
+	if p.recorder, err = newRecorder(f.Name(), realClock{}, stdLogger{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"foo", "bar"} {
+		if err := p.CreatePod(ctx, makePod("default", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.DeletePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := os.Open(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trace.Close()
+
+	replayed := newTestProvider(t)
+	if err := replayed.replay(ctx, trace, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	pods, err := replayed.GetPods(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Name != "bar" {
+		t.Errorf("Got %d pods, expected only pod bar", len(pods))
+	}
+}
+
+func TestReplayPathConflict(t *testing.T) {
+	for _, config := range []MockConfig{
+		{ReplayPath: "trace.json", RecordPath: "trace.json"},
+		{ReplayPath: "trace.json", AuditPath: "./trace.json"},
+	} {
+		if _, err := NewMockProviderWithConfig(config, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+			t.Errorf("Expected an error for replaying %s while writing to it", config.ReplayPath)
+		}
+	}
+}
+
+func TestReplayOpenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	config := MockConfig{AdminAddr: addr, ReplayPath: "does-not-exist.json"}
+	if _, err := NewMockProviderWithConfig(config, "vk", "Linux", "10.0.0.1", 10250); err == nil {
+		t.Fatal("Expected an error for a missing replay file")
+	}
+
+	// The admin listener must have been released.
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Expected the admin address to be released: %v", err)
+	}
+	l.Close()
+}
+
+func TestAudit(t *testing.T) {
+	f, err := ioutil.TempFile("", "mock-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	p := newTestProvider(t)
+	if p.auditor, err = newRecorder(f.Name(), realClock{}, stdLogger{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetPodStatus(ctx, "default", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreatePod(ctx, makePod("", "bar")); err == nil {
+		t.Fatal("Expected an error for a pod without namespace")
+	}
+
+	audit, err := os.Open(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	var records []traceRecord
+	scanner := bufio.NewScanner(audit)
+	for scanner.Scan() {
+		var rec traceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+
+	expected := []string{operationCreatePod, operationGetPodStatus, operationCreatePod}
+	if len(records) != len(expected) {
+		t.Fatalf("Got %d records, expected %d", len(records), len(expected))
+	}
+	for i, op := range expected {
+		if records[i].Operation != op {
+			t.Errorf("Got operation %s, expected %s", records[i].Operation, op)
+		}
+	}
+	if records[1].Status == nil || records[1].Name != "foo" {
+		t.Errorf("Got record %+v, expected the status of pod foo", records[1])
+	}
+	if records[2].Error == "" {
+		t.Error("Expected the error of the failed operation to be audited")
+	}
+
+	if _, err := audit.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	replayed := newTestProvider(t)
+	if err := replayed.replay(ctx, audit, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if pod, err := replayed.GetPod(ctx, "default", "foo"); err != nil || pod == nil {
+		t.Errorf("Expected pod foo to be replayed from the audit file, got %v (%v)", pod, err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	f, err := ioutil.TempFile("", "mock-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	p, err := NewMockProviderWithConfig(MockConfig{AdminAddr: "127.0.0.1:0", AuditPath: f.Name()}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DeletePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 1 {
+		t.Errorf("Got %d records, expected only the one audited before closing", n)
+	}
+}
+
+func TestAuditDeletePod(t *testing.T) {
+	f, err := ioutil.TempFile("", "mock-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	p := newTestProvider(t)
+	if p.auditor, err = newRecorder(f.Name(), realClock{}, stdLogger{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	pod := makePod("default", "foo")
+	if err := p.CreatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DeletePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	var rec traceRecord
+	if err := json.Unmarshal(lines[len(lines)-1], &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Operation != operationDeletePod {
+		t.Fatalf("Got operation %s, expected %s", rec.Operation, operationDeletePod)
+	}
+	if rec.Status == nil || len(rec.Status.ContainerStatuses) == 0 {
+		t.Fatalf("Got status %+v, expected the final status of the pod", rec.Status)
+	}
+	terminated := rec.Status.ContainerStatuses[0].State.Terminated
+	if terminated == nil || terminated.Reason != "Killed" {
+		t.Errorf("Got container state %+v, expected the container to be killed", rec.Status.ContainerStatuses[0].State)
+	}
+	if rec.Status.PodIP == "" {
+		t.Error("Expected the pod IP in the final status")
+	}
+}
@@ -0,0 +1,125 @@
+package mock
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// startupDelayAnnotation overrides the startup delay of a pod with a duration parsed by time.ParseDuration.
+const startupDelayAnnotation = "mock.virtual-kubelet.io/startup-delay"
+
+// StartupDelayConfig configures the time a pod stays pending before it is reported running.
+// Durations are parsed with time.ParseDuration.
+type StartupDelayConfig struct {
+	// Sandbox is the time taken to create the sandbox of every pod.
+	Sandbox string `json:"sandbox,omitempty"`
+
+	// VolumeMount is the time taken to mount the volumes of pods having some.
+	VolumeMount string `json:"volumeMount,omitempty"`
+
+	// ImagePull is the time taken to pull each distinct image of the containers of a pod.
+	ImagePull string `json:"imagePull,omitempty"`
+}
+
+// startupDelay computes the startup delays of pods.
+type startupDelay struct {
+	sandbox     time.Duration
+	volumeMount time.Duration
+	imagePull   time.Duration
+
+	// disabled makes every pod start right away, including those with the startup delay annotation.
+	disabled bool
+}
+
+func newStartupDelay(config *StartupDelayConfig, disabled bool) (startupDelay, error) {
+	d := startupDelay{disabled: disabled}
+	if config == nil {
+		return d, nil
+	}
+
+	for _, f := range []struct {
+		name  string
+		value string
+		d     *time.Duration
+	}{
+		{"sandbox", config.Sandbox, &d.sandbox},
+		{"volumeMount", config.VolumeMount, &d.volumeMount},
+		{"imagePull", config.ImagePull, &d.imagePull},
+	} {
+		if f.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(f.value)
+		if err != nil || v < 0 {
+			return d, fmt.Errorf("Invalid startup delay %s %v", f.name, f.value)
+		}
+		*f.d = v
+	}
+
+	return d, nil
+}
+
+// annotatedStartupDelay returns the startup delay the annotation of the pod overrides, and whether it is set.
+// A malformed or negative delay is an error.
+func annotatedStartupDelay(pod *v1.Pod) (time.Duration, bool, error) {
+	v, ok := pod.Annotations[startupDelayAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+	delay, err := time.ParseDuration(v)
+	if err != nil || delay < 0 {
+		return 0, true, fmt.Errorf("invalid %s annotation %q", startupDelayAnnotation, v)
+	}
+	return delay, true, nil
+}
+
+// of returns the startup delay of the pod. Pods with a malformed startup delay annotation are rejected by
+// validatePod, so it is only taken into account if it is valid.
+func (d startupDelay) of(pod *v1.Pod) time.Duration {
+	if d.disabled {
+		return 0
+	}
+	if delay, ok, err := annotatedStartupDelay(pod); ok && err == nil {
+		return delay
+	}
+
+	delay := d.sandbox
+	if len(pod.Spec.Volumes) > 0 {
+		delay += d.volumeMount
+	}
+
+	images := make(map[string]bool)
+	for _, c := range pod.Spec.Containers {
+		images[c.Image] = true
+	}
+	delay += time.Duration(len(images)) * d.imagePull
+
+	return delay
+}
+
+// pendingPodStatus builds the status of a pod whose containers are being created.
+func pendingPodStatus(pod *v1.Pod, accepted metav1.Time) *v1.PodStatus {
+	status := &v1.PodStatus{
+		Phase:      v1.PodPending,
+		HostIP:     "1.2.3.4",
+		StartTime:  &accepted,
+		Conditions: podConditions(pod, false, containersNotReadyReason, accepted),
+	}
+
+	for _, container := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
+			Name:  container.Name,
+			Image: container.Image,
+			State: v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{
+					Reason: "ContainerCreating",
+				},
+			},
+		})
+	}
+
+	return status
+}
@@ -0,0 +1,77 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestStartupDelayOf(t *testing.T) {
+	d, err := newStartupDelay(&StartupDelayConfig{Sandbox: "1s", VolumeMount: "2s", ImagePull: "10s"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod := makePod("default", "foo")
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "d", Image: "busybox"}, v1.Container{Name: "e", Image: "nginx"})
+	if delay := d.of(pod); delay != 21*time.Second {
+		t.Errorf("Got delay %v, expected 21s", delay)
+	}
+
+	pod.Spec.Volumes = []v1.Volume{{Name: "v"}}
+	if delay := d.of(pod); delay != 23*time.Second {
+		t.Errorf("Got delay %v, expected 23s", delay)
+	}
+
+	pod.Annotations = map[string]string{startupDelayAnnotation: "5s"}
+	if delay := d.of(pod); delay != 5*time.Second {
+		t.Errorf("Got delay %v, expected 5s", delay)
+	}
+
+	if _, err := newStartupDelay(&StartupDelayConfig{ImagePull: "soon"}, false); err == nil {
+		t.Error("Expected an error for an invalid image pull delay")
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	notified := make(chan *v1.Pod, 2)
+	p.NotifyPods(ctx, func(pod *v1.Pod) { notified <- pod })
+
+	pod := makePod("default", "foo")
+	pod.Annotations = map[string]string{startupDelayAnnotation: "50ms"}
+	if err := p.CreatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodPending {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodPending)
+	}
+
+	for _, expected := range []v1.PodPhase{v1.PodPending, v1.PodRunning} {
+		select {
+		case pod := <-notified:
+			if pod.Status.Phase != expected {
+				t.Errorf("Got notified phase %s, expected %s", pod.Status.Phase, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the %s notification", expected)
+		}
+	}
+
+	status, err = p.GetPodStatus(ctx, "default", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != v1.PodRunning {
+		t.Errorf("Got phase %s, expected %s", status.Phase, v1.PodRunning)
+	}
+}
@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+// startSpan starts a span for a provider operation on a pod.
+// Spans are exported with the exporters configured for virtual-kubelet, as child spans of the caller's ones.
+func startSpan(ctx context.Context, op, namespace, name string) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, "mock."+op)
+	span.AddAttributes(
+		trace.StringAttribute("namespace", namespace),
+		trace.StringAttribute("name", name),
+	)
+	return ctx, span
+}
+
+// endSpan ends the span of a provider operation, recording its error if any.
+func endSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
@@ -0,0 +1,56 @@
+package mock
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestOperationSpans(t *testing.T) {
+	r := &spanRecorder{}
+	trace.RegisterExporter(r)
+	defer trace.UnregisterExporter(r)
+
+	p := newTestProvider(t)
+	ctx, span := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+
+	if err := p.CreatePod(ctx, makePod("default", "foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreatePod(ctx, makePod("", "bar")); err == nil {
+		t.Fatal("Expected an error for a pod without namespace")
+	}
+	span.End()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.spans) != 3 {
+		t.Fatalf("Got %d spans, expected 3", len(r.spans))
+	}
+	for i, expected := range []int32{trace.StatusCodeOK, trace.StatusCodeUnknown} {
+		s := r.spans[i]
+		if s.Name != "mock.CreatePod" {
+			t.Errorf("Got span %s, expected mock.CreatePod", s.Name)
+		}
+		if s.ParentSpanID != span.SpanContext().SpanID {
+			t.Errorf("Got parent span %s, expected %s", s.ParentSpanID, span.SpanContext().SpanID)
+		}
+		if s.Status.Code != expected {
+			t.Errorf("Got status code %d, expected %d", s.Status.Code, expected)
+		}
+	}
+}
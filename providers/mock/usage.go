@@ -0,0 +1,153 @@
+package mock
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// namespaceUsage is the resources requested by the pods of a namespace which hold them on the node,
+// i.e. which are neither terminated, rejected nor being deleted. The usage of each namespace is kept up to
+// date as pods are admitted and release their resources, rather than computed from every pod.
+type namespaceUsage struct {
+	Pods     int             `json:"pods"`
+	Requests v1.ResourceList `json:"requests"`
+	Limits   v1.ResourceList `json:"limits"`
+}
+
+// accountedPod is the usage a pod holding resources adds to its namespace.
+type accountedPod struct {
+	namespace string
+	usage     *namespaceUsage
+}
+
+// accountPod adds the resources of the pod, which was just admitted at key, to the usage of its namespace.
+// p.mu must be held.
+func (p *MockProvider) accountPod(key string, pod *v1.Pod) {
+	u := newNamespaceUsage()
+	u.add(pod, p.resourceDefaults, p.podOverhead(pod))
+	p.accounted[key] = accountedPod{namespace: pod.Namespace, usage: u}
+
+	ns, ok := p.usage[pod.Namespace]
+	if !ok {
+		ns = newNamespaceUsage()
+		p.usage[pod.Namespace] = ns
+	}
+	ns.merge(u, 1)
+}
+
+// releasePod removes the resources of the pod stored at key from the usage of its namespace, once the pod
+// is terminated, is being deleted or is removed. It does nothing if the pod holds no resources. p.mu must be held.
+func (p *MockProvider) releasePod(key string) {
+	a, ok := p.accounted[key]
+	if !ok {
+		return
+	}
+	delete(p.accounted, key)
+
+	ns := p.usage[a.namespace]
+	ns.merge(a.usage, -1)
+	if ns.Pods == 0 {
+		delete(p.usage, a.namespace)
+	}
+}
+
+// usageByNamespace returns a copy of the resources held by the pods, by namespace.
+// p.mu must be held.
+func (p *MockProvider) usageByNamespace() map[string]*namespaceUsage {
+	usage := make(map[string]*namespaceUsage, len(p.usage))
+	for namespace, u := range p.usage {
+		c := newNamespaceUsage()
+		c.merge(u, 1)
+		usage[namespace] = c
+	}
+	return usage
+}
+
+// NamespaceUsage returns the resources used by the pods of each namespace: the requests of the pods holding
+// resources on the node, and their number.
+func (p *MockProvider) NamespaceUsage(ctx context.Context) map[string]v1.ResourceList {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	usage := make(map[string]v1.ResourceList, len(p.usage))
+	for namespace, u := range p.usage {
+		used := make(v1.ResourceList, len(u.Requests)+1)
+		for name, q := range u.Requests {
+			used[name] = q.DeepCopy()
+		}
+		used[v1.ResourcePods] = *resource.NewQuantity(int64(u.Pods), resource.DecimalSI)
+		usage[namespace] = used
+	}
+	return usage
+}
+
+func newNamespaceUsage() *namespaceUsage {
+	return &namespaceUsage{Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+}
+
+// add accounts the resources of the pod, once the defaults are applied to its containers.
+// The overhead of the pod is added to its requests, and to the limits of the resources it limits.
+func (u *namespaceUsage) add(pod *v1.Pod, defaults resourceDefaults, overhead v1.ResourceList) {
+	u.Pods++
+	limits := v1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		r := defaults.apply(c.Resources)
+		addResources(u.Requests, r.Requests)
+		addResources(limits, r.Limits)
+	}
+
+	addResources(u.Requests, overhead)
+	for name, q := range overhead {
+		if limit, ok := limits[name]; ok {
+			limit.Add(q)
+			limits[name] = limit
+		}
+	}
+	addResources(u.Limits, limits)
+}
+
+// merge adds the usage other to u if sign is 1, or subtracts it if sign is -1.
+func (u *namespaceUsage) merge(other *namespaceUsage, sign int) {
+	u.Pods += sign * other.Pods
+	mergeResources(u.Requests, other.Requests, sign)
+	mergeResources(u.Limits, other.Limits, sign)
+}
+
+// quotaUsage returns the usage under the resource names of ResourceQuotas, where requests.<name>
+// and <name> are the requests of a resource, and limits.<name> its limits.
+func (u *namespaceUsage) quotaUsage() v1.ResourceList {
+	usage := v1.ResourceList{v1.ResourcePods: *resource.NewQuantity(int64(u.Pods), resource.DecimalSI)}
+	for name, q := range u.Requests {
+		usage[name] = q
+		usage[v1.ResourceName("requests."+string(name))] = q
+	}
+	for name, q := range u.Limits {
+		usage[v1.ResourceName("limits."+string(name))] = q
+	}
+	return usage
+}
+
+// addResources adds the quantities of src to dst.
+func addResources(dst, src v1.ResourceList) {
+	mergeResources(dst, src, 1)
+}
+
+// mergeResources adds the quantities of src to dst if sign is 1, or subtracts them if sign is -1.
+// Resources whose quantity drops to zero are removed from dst.
+func mergeResources(dst, src v1.ResourceList, sign int) {
+	for name, q := range src {
+		sum := dst[name]
+		if sign < 0 {
+			sum.Sub(q)
+		} else {
+			sum.Add(q)
+		}
+		if sum.IsZero() && sign < 0 {
+			delete(dst, name)
+			continue
+		}
+		dst[name] = sum
+	}
+}
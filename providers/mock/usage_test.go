@@ -0,0 +1,103 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkUsage compares the usage index of the provider with the usage of the pods holding resources.
+func checkUsage(t *testing.T, p *MockProvider, step string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	expected := make(map[string]*namespaceUsage)
+	for key, pod := range p.pods {
+		if !p.holdsResources(key) {
+			continue
+		}
+		u, ok := expected[pod.Namespace]
+		if !ok {
+			u = newNamespaceUsage()
+			expected[pod.Namespace] = u
+		}
+		u.add(pod, p.resourceDefaults, p.podOverhead(pod))
+	}
+
+	usage := p.usageByNamespace()
+	if len(usage) != len(expected) {
+		t.Errorf("%s: Got usage of %d namespaces, expected %d", step, len(usage), len(expected))
+	}
+	for namespace, e := range expected {
+		u, ok := usage[namespace]
+		if !ok {
+			t.Errorf("%s: Expected usage of namespace %s", step, namespace)
+			continue
+		}
+		if u.Pods != e.Pods || !equalResources(u.Requests, e.Requests) || !equalResources(u.Limits, e.Limits) {
+			t.Errorf("%s: Got usage %+v of namespace %s, expected %+v", step, u, namespace, e)
+		}
+	}
+}
+
+func TestUsageIndex(t *testing.T) {
+	clock := newFakeClock()
+	ctx := context.Background()
+
+	p, err := NewMockProviderWithConfig(MockConfig{GracefulDeletion: boolPtr(true)}, "vk", "Linux", "10.0.0.1", 10250, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		pod := makePod(fmt.Sprintf("ns-%d", i%2), fmt.Sprintf("pod-%d", i))
+		pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}
+		if err := p.CreatePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkUsage(t, p, "created")
+
+	resized := makePod("ns-0", "pod-0")
+	resized.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}
+	if err := p.UpdatePod(ctx, resized); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(t, p, "resized")
+
+	if code := doAdminRequest(t, p, "POST", "/pods/ns-0/pod-2/terminate", `{"exitCode": 1}`); code != http.StatusOK {
+		t.Fatalf("Got status %d terminating pod-2", code)
+	}
+	checkUsage(t, p, "terminated")
+
+	grace := int64(30)
+	deleted := makePod("ns-1", "pod-1")
+	deleted.DeletionTimestamp = &metav1.Time{Time: clock.Now()}
+	deleted.DeletionGracePeriodSeconds = &grace
+	if err := p.DeletePod(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(t, p, "terminating")
+
+	clock.Advance(time.Duration(grace) * time.Second)
+	checkUsage(t, p, "deleted")
+
+	for _, pod := range []*v1.Pod{makePod("ns-0", "pod-0"), makePod("ns-0", "pod-2"), makePod("ns-1", "pod-3")} {
+		if err := p.DeletePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkUsage(t, p, "all deleted")
+	p.mu.RLock()
+	n := len(p.usageByNamespace())
+	p.mu.RUnlock()
+	if n != 0 {
+		t.Errorf("Got usage of %d namespaces, expected none once every pod is deleted", n)
+	}
+}
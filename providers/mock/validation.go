@@ -0,0 +1,100 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"k8s.io/api/core/v1"
+)
+
+// Reasons of the status of pods rejected because of their spec. The kubelet reports unexpected
+// admission failures and host network pods it cannot run with these reasons.
+const (
+	invalidPodSpecReason        = providers.PodReasonUnexpectedAdmissionError
+	hostNetworkNotAllowedReason = "HostNetworkNotSupported"
+)
+
+// validatePod checks that the pod can be run by the provider. If it cannot, it returns the rejected status of the pod.
+func (p *MockProvider) validatePod(pod *v1.Pod) *v1.PodStatus {
+	if len(pod.Spec.Containers) == 0 {
+		return rejectedPodStatus(invalidPodSpecReason, "Pod was rejected: pod has no containers")
+	}
+
+	if pod.Spec.HostNetwork && isEnabled(p.config.DisableHostNetwork) {
+		return rejectedPodStatus(hostNetworkNotAllowedReason, "Pod was rejected: host network is not allowed on this node")
+	}
+
+	if _, _, err := annotatedStartupDelay(pod); err != nil {
+		return rejectedPodStatus(invalidPodSpecReason, fmt.Sprintf("Pod was rejected: %v", err))
+	}
+
+	containers := append(append([]v1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		if err := validateResources(p.resourceDefaults.apply(c.Resources)); err != nil {
+			return rejectedPodStatus(invalidPodSpecReason, fmt.Sprintf("Pod was rejected: container %s: %v", c.Name, err))
+		}
+	}
+	return nil
+}
+
+// validateResources checks that the resource requirements of a container are not negative,
+// and that requests do not exceed limits. Huge pages, which cannot be overcommitted, must be requested
+// up to their limit.
+func validateResources(r v1.ResourceRequirements) error {
+	for name, q := range r.Requests {
+		if q.Sign() < 0 {
+			return fmt.Errorf("negative %s request %s", name, q.String())
+		}
+		if limit, ok := r.Limits[name]; strings.HasPrefix(string(name), v1.ResourceHugePagesPrefix) && (!ok || q.Cmp(limit) != 0) {
+			return fmt.Errorf("%s request %s must equal its limit", name, q.String())
+		}
+		if limit, ok := r.Limits[name]; ok && q.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request %s exceeds limit %s", name, q.String(), limit.String())
+		}
+	}
+	for name, q := range r.Limits {
+		if q.Sign() < 0 {
+			return fmt.Errorf("negative %s limit %s", name, q.String())
+		}
+	}
+	return nil
+}
+
+// admissionChanged reports whether an update of the pod changes what it is admitted on: the containers and
+// their resources and host ports, its host network and its runtime class, whose overhead it is accounted with.
+func admissionChanged(old, pod *v1.Pod) bool {
+	if old.Spec.HostNetwork != pod.Spec.HostNetwork || !reflect.DeepEqual(old.Spec.RuntimeClassName, pod.Spec.RuntimeClassName) {
+		return true
+	}
+	return containersChanged(old.Spec.InitContainers, pod.Spec.InitContainers) || containersChanged(old.Spec.Containers, pod.Spec.Containers)
+}
+
+func containersChanged(old, containers []v1.Container) bool {
+	if len(old) != len(containers) {
+		return true
+	}
+	for i := range containers {
+		if !equalResources(old[i].Resources.Requests, containers[i].Resources.Requests) ||
+			!equalResources(old[i].Resources.Limits, containers[i].Resources.Limits) ||
+			!reflect.DeepEqual(old[i].Ports, containers[i].Ports) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalResources compares resource lists by value, as equal quantities can be represented differently.
+func equalResources(a, b v1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, q := range a {
+		other, ok := b[name]
+		if !ok || q.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
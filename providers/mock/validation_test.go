@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestValidatePod(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewMockProviderWithConfig(MockConfig{DisableHostNetwork: boolPtr(true)}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negative := makePod("default", "negative")
+	negative.Spec.Containers[0].Resources.Requests = v1.ResourceList{v1.ResourceCPU: resource.MustParse("-1")}
+
+	overLimit := makePod("default", "over-limit")
+	overLimit.Spec.Containers[0].Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+		Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+
+	hugePages := makePod("default", "huge-pages")
+	hugePages.Spec.Containers[0].Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{"hugepages-2Mi": resource.MustParse("64Mi")},
+		Limits:   v1.ResourceList{"hugepages-2Mi": resource.MustParse("128Mi")},
+	}
+
+	hostNetwork := makePod("default", "host-network")
+	hostNetwork.Spec.HostNetwork = true
+
+	noContainers := makePod("default", "no-containers")
+	noContainers.Spec.Containers = nil
+
+	malformedDelay := makePod("default", "malformed-delay")
+	malformedDelay.Annotations = map[string]string{startupDelayAnnotation: "10 seconds"}
+
+	negativeDelay := makePod("default", "negative-delay")
+	negativeDelay.Annotations = map[string]string{startupDelayAnnotation: "-1s"}
+
+	valid := makePod("default", "valid")
+	valid.Spec.Containers[0].Resources = v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi"), "hugepages-1Gi": resource.MustParse("2Gi")},
+		Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi"), "hugepages-1Gi": resource.MustParse("2Gi")},
+	}
+
+	for _, c := range []struct {
+		pod    *v1.Pod
+		phase  v1.PodPhase
+		reason string
+	}{
+		{negative, v1.PodFailed, invalidPodSpecReason},
+		{overLimit, v1.PodFailed, invalidPodSpecReason},
+		{hugePages, v1.PodFailed, invalidPodSpecReason},
+		{hostNetwork, v1.PodFailed, hostNetworkNotAllowedReason},
+		{noContainers, v1.PodFailed, invalidPodSpecReason},
+		{malformedDelay, v1.PodFailed, invalidPodSpecReason},
+		{negativeDelay, v1.PodFailed, invalidPodSpecReason},
+		{valid, v1.PodRunning, ""},
+	} {
+		if err := p.CreatePod(ctx, c.pod); err != nil {
+			t.Fatal(err)
+		}
+		status, err := p.GetPodStatus(ctx, c.pod.Namespace, c.pod.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Phase != c.phase || status.Reason != c.reason {
+			t.Errorf("Got phase %s (%s) for %s, expected %s (%s)", status.Phase, status.Reason, c.pod.Name, c.phase, c.reason)
+		}
+	}
+}
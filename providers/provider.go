@@ -61,3 +61,42 @@ type Provider interface {
 type PodMetricsProvider interface {
 	GetStatsSummary(context.Context) (*stats.Summary, error)
 }
+
+// PodNotifier is an optional interface that providers can implement to push pod status
+// changes to the virtual kubelet as soon as they happen, instead of waiting for the next
+// GetPodStatus poll.
+type PodNotifier interface {
+	// NotifyPods instructs the notifier to call the passed in function with the pod,
+	// its Status set to the current one, every time the status of a pod changes.
+	//
+	// NotifyPods should not block callers.
+	NotifyPods(context.Context, func(*v1.Pod))
+}
+
+// NodeLeaseProvider is an optional interface that providers can implement to control the
+// renewal of the node lease, e.g. to simulate a loss of the node heartbeats.
+type NodeLeaseProvider interface {
+	// ShouldRenewNodeLease reports whether the node lease should be renewed now.
+	ShouldRenewNodeLease(context.Context) bool
+}
+
+// PodCIDRProvider is an optional interface that providers can implement to report the range
+// the IPs of the pods running on the node are allocated from.
+type PodCIDRProvider interface {
+	// PodCIDR returns the CIDR reported as the PodCIDR of the node.
+	PodCIDR(context.Context) string
+}
+
+// NamespaceUsageProvider is an optional interface that providers can implement to report the resources
+// used on the node by the pods of each namespace, which the virtual kubelet exports as metrics.
+type NamespaceUsageProvider interface {
+	// NamespaceUsage returns the resources used by the pods of each namespace, keyed by namespace.
+	NamespaceUsage(context.Context) map[string]v1.ResourceList
+}
+
+// NodeInfoProvider is an optional interface that providers can implement to report the system info of the node.
+// Fields left empty are filled with the defaults of virtual-kubelet.
+type NodeInfoProvider interface {
+	// NodeInfo returns the system info reported in the node status.
+	NodeInfo(context.Context) v1.NodeSystemInfo
+}
@@ -12,11 +12,16 @@ func init() {
 }
 
 func initMock(cfg InitConfig) (providers.Provider, error) {
+	var opts []mock.MockProviderOption
+	if cfg.DeterministicFast {
+		opts = append(opts, mock.WithDeterministicFast())
+	}
 	return mock.NewMockProvider(
 		cfg.ConfigPath,
 		cfg.NodeName,
 		cfg.OperatingSystem,
 		cfg.InternalIP,
 		cfg.DaemonPort,
+		opts...,
 	)
 }
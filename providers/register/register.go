@@ -17,6 +17,10 @@ type InitConfig struct {
 	InternalIP      string
 	DaemonPort      int32
 	ResourceManager *manager.ResourceManager
+
+	// DeterministicFast asks simulation providers to turn off their chaos, injected latency, startup delays
+	// and rate limiting. Other providers ignore it.
+	DeterministicFast bool
 }
 
 type initFunc func(InitConfig) (providers.Provider, error)
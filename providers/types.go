@@ -7,6 +7,25 @@ const (
 	OperatingSystemWindows = "Windows"
 )
 
+// Reasons of the status of pods rejected by the provider, which match the ones of the kubelet.
+const (
+	// PodReasonExceededQuota is the reason of pods rejected because they exceed the quota of their namespace.
+	PodReasonExceededQuota = "ExceededQuota"
+	// PodReasonOutOfPrefix prefixes the reason of pods rejected because the node does not have enough
+	// of a resource left, e.g. OutOfcpu.
+	PodReasonOutOfPrefix = "OutOf"
+	// PodReasonUnexpectedAdmissionError is the reason of pods rejected by an admission handler, e.g.
+	// for an invalid spec, or by the CPU manager.
+	PodReasonUnexpectedAdmissionError = "UnexpectedAdmissionError"
+	// PodReasonEvicted is the reason of pods evicted from the node, e.g. when it is low on a resource.
+	PodReasonEvicted = "Evicted"
+	// PodReasonPreempting is the reason of pods terminated to make room for critical pods.
+	PodReasonPreempting = "Preempting"
+	// PodMessageNotEnoughCPUs is part of the message of pods rejected by the static CPU manager policy
+	// because not enough cores are left to assign them exclusively.
+	PodMessageNotEnoughCPUs = "not enough cpus available to satisfy request"
+)
+
 type OperatingSystems map[string]bool
 
 var (
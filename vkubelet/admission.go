@@ -0,0 +1,136 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// Reasons of the pods rejected because they don't fit the node, which match the ones of the kubelet.
+	podStatusReasonMatchNodeSelector = "MatchNodeSelector"
+	podStatusReasonToleratesTaints   = "PodToleratesNodeTaints"
+
+	// nodeNameField is the only field node selector terms can match.
+	nodeNameField = "metadata.name"
+)
+
+// setNode remembers the last known node object of the virtual node, which pods are admitted against.
+func (s *Server) setNode(node *corev1.Node) {
+	s.nodeMu.Lock()
+	defer s.nodeMu.Unlock()
+	s.node = node
+}
+
+// admitPod checks that the pod fits the node: its node selector and required node affinity must match
+// the labels of the node, and it must tolerate the taints of the node. NoSchedule taints only apply to pods
+// placed by a scheduler, as pods bound by setting their node name bypass them. This surfaces pods which were
+// scheduled to the node by mistake.
+// It returns the reason and the message of the rejection of the pod, or false if the pod is admitted.
+func (s *Server) admitPod(pod *corev1.Pod) (reason, message string, rejected bool) {
+	s.nodeMu.Lock()
+	node := s.node
+	s.nodeMu.Unlock()
+	if node == nil {
+		return "", "", false
+	}
+
+	nodeLabels := labels.Set(node.Labels)
+	for k, v := range pod.Spec.NodeSelector {
+		if nodeLabels[k] != v {
+			return podStatusReasonMatchNodeSelector, fmt.Sprintf("Pod node selector %s=%s does not match the node labels", k, v), true
+		}
+	}
+
+	if a := pod.Spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		if !matchNodeSelectorTerms(a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, node) {
+			return podStatusReasonMatchNodeSelector, "Pod node affinity does not match the node", true
+		}
+	}
+
+	_, scheduled := podBindingTime(pod)
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		switch {
+		case taint.Effect == corev1.TaintEffectNoExecute:
+		case taint.Effect == corev1.TaintEffectNoSchedule && scheduled:
+		default:
+			continue
+		}
+		if !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return podStatusReasonToleratesTaints, fmt.Sprintf("Pod does not tolerate the node taint %s", taint.ToString()), true
+		}
+	}
+
+	return "", "", false
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNodeSelectorTerms returns whether the node matches one of the terms. The requirements of a term
+// must all be met, and a term with no requirement matches no node, as for the scheduler.
+func matchNodeSelectorTerms(terms []corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	nodeLabels := labels.Set(node.Labels)
+	nodeFields := labels.Set{nodeNameField: node.Name}
+
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+		if matchNodeSelectorRequirements(term.MatchExpressions, nodeLabels) && matchNodeSelectorRequirements(term.MatchFields, nodeFields) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodeSelectorRequirements(requirements []corev1.NodeSelectorRequirement, set labels.Set) bool {
+	for _, r := range requirements {
+		var op selection.Operator
+		switch r.Operator {
+		case corev1.NodeSelectorOpIn:
+			op = selection.In
+		case corev1.NodeSelectorOpNotIn:
+			op = selection.NotIn
+		case corev1.NodeSelectorOpExists:
+			op = selection.Exists
+		case corev1.NodeSelectorOpDoesNotExist:
+			op = selection.DoesNotExist
+		case corev1.NodeSelectorOpGt:
+			op = selection.GreaterThan
+		case corev1.NodeSelectorOpLt:
+			op = selection.LessThan
+		default:
+			return false
+		}
+
+		req, err := labels.NewRequirement(r.Key, op, r.Values)
+		if err != nil || !req.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// rejectPod fails a pod which does not fit the node, without creating it in the provider.
+// The rejection event is recorded by updatePodStatus.
+func (s *Server) rejectPod(ctx context.Context, pod *corev1.Pod, reason, message string) {
+	log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).WithField("reason", reason).Warn("Rejecting pod")
+
+	status := pod.Status.DeepCopy()
+	status.Phase = corev1.PodFailed
+	status.Reason = reason
+	status.Message = message
+	s.updatePodStatus(ctx, pod, status)
+}
@@ -0,0 +1,62 @@
+package vkubelet
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAdmitPod(t *testing.T) {
+	s := &Server{}
+	s.setNode(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "vk",
+			Labels: map[string]string{"type": "virtual-kubelet", "zone": "a", "gpus": "4"},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "virtual-kubelet.io/provider", Value: "mock", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectPreferNoSchedule},
+			},
+		},
+	})
+
+	scheduled := []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()}}
+	toleration := []corev1.Toleration{{Key: "virtual-kubelet.io/provider", Operator: corev1.TolerationOpExists}}
+	affinity := func(terms ...corev1.NodeSelectorTerm) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		}}
+	}
+	expression := func(key string, op corev1.NodeSelectorOperator, values ...string) corev1.NodeSelectorTerm {
+		return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: op, Values: values}}}
+	}
+
+	for _, c := range []struct {
+		name       string
+		spec       corev1.PodSpec
+		conditions []corev1.PodCondition
+		expected   string
+	}{
+		{name: "no constraint"},
+		{name: "matching node selector", spec: corev1.PodSpec{NodeSelector: map[string]string{"zone": "a"}}},
+		{name: "mismatching node selector", spec: corev1.PodSpec{NodeSelector: map[string]string{"zone": "b"}}, expected: podStatusReasonMatchNodeSelector},
+		{name: "matching affinity", spec: corev1.PodSpec{Affinity: affinity(expression("zone", corev1.NodeSelectorOpIn, "a", "b"))}},
+		{name: "matching second affinity term", spec: corev1.PodSpec{Affinity: affinity(expression("zone", corev1.NodeSelectorOpIn, "b"), expression("gpus", corev1.NodeSelectorOpGt, "2"))}},
+		{name: "mismatching affinity", spec: corev1.PodSpec{Affinity: affinity(expression("zone", corev1.NodeSelectorOpNotIn, "a"))}, expected: podStatusReasonMatchNodeSelector},
+		{name: "missing affinity label", spec: corev1.PodSpec{Affinity: affinity(expression("disk", corev1.NodeSelectorOpExists))}, expected: podStatusReasonMatchNodeSelector},
+		{name: "matching affinity field", spec: corev1.PodSpec{Affinity: affinity(corev1.NodeSelectorTerm{
+			MatchFields: []corev1.NodeSelectorRequirement{{Key: nodeNameField, Operator: corev1.NodeSelectorOpIn, Values: []string{"vk"}}},
+		})}},
+		{name: "empty affinity term", spec: corev1.PodSpec{Affinity: affinity(corev1.NodeSelectorTerm{})}, expected: podStatusReasonMatchNodeSelector},
+		{name: "scheduled with toleration", spec: corev1.PodSpec{Tolerations: toleration}, conditions: scheduled},
+		{name: "scheduled without toleration", conditions: scheduled, expected: podStatusReasonToleratesTaints},
+	} {
+		pod := &corev1.Pod{Spec: c.spec, Status: corev1.PodStatus{Conditions: c.conditions}}
+		reason, _, rejected := s.admitPod(pod)
+		if rejected != (c.expected != "") || reason != c.expected {
+			t.Errorf("%s: Got rejected %v (%s), expected %v (%s)", c.name, rejected, reason, c.expected != "", c.expected)
+		}
+	}
+}
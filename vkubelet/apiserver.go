@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/http/pprof"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
@@ -23,11 +24,20 @@ func PodHandler(p providers.Provider) http.Handler {
 	return r
 }
 
-// MetricsSummaryHandler creates an http handler for serving pod metrics.
+// MetricsSummaryHandler creates an http handler for serving pod metrics, and the latency summary,
+// pod lifecycle counts and namespace resources of the virtual kubelet on /metrics/latency,
+// /metrics/pods and /metrics/namespaces.
+// It also serves liveness and readiness probes on /healthz and /readyz.
 //
 // If the passed in provider does not implement providers.PodMetricsProvider,
 // it will create handlers that just serves http.StatusNotImplemented
 func MetricsSummaryHandler(p providers.Provider) http.Handler {
+	return metricsHandler(p, nil, false)
+}
+
+// metricsHandler creates the handler of MetricsSummaryHandler. /readyz fails while ready returns false.
+// A nil ready is always ready. If profiling is set, the runtime profiles are served on /debug/pprof/ as well.
+func metricsHandler(p providers.Provider, ready func() bool, profiling bool) http.Handler {
 	r := mux.NewRouter()
 
 	const summaryRoute = "/stats/summary"
@@ -42,6 +52,20 @@ func MetricsSummaryHandler(p providers.Provider) http.Handler {
 
 	r.Handle(summaryRoute, ochttp.WithRouteTag(h, "PodStatsSummaryHandler")).Methods("GET")
 	r.Handle(summaryRoute+"/", ochttp.WithRouteTag(h, "PodStatsSummaryHandler")).Methods("GET")
+	r.Handle("/metrics/latency", ochttp.WithRouteTag(http.HandlerFunc(LatencyHandler), "LatencyHandler")).Methods("GET")
+	r.Handle("/metrics/pods", ochttp.WithRouteTag(http.HandlerFunc(PodLifecycleHandler), "PodLifecycleHandler")).Methods("GET")
+	r.Handle("/metrics/namespaces", ochttp.WithRouteTag(http.HandlerFunc(NamespaceResourcesHandler), "NamespaceResourcesHandler")).Methods("GET")
+
+	r.HandleFunc("/healthz", probeHandler(nil)).Methods("GET")
+	r.HandleFunc("/readyz", probeHandler(ready)).Methods("GET")
+
+	if profiling {
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	}
 
 	r.NotFoundHandler = http.HandlerFunc(NotFound)
 	return r
@@ -56,11 +80,26 @@ func KubeletServerStart(p providers.Provider, l net.Listener, cert, key string)
 
 // MetricsServerStart starts an HTTP server on the provided addr for serving the kubelset summary stats API.
 func MetricsServerStart(p providers.Provider, l net.Listener) {
-	if err := http.Serve(l, InstrumentHandler(MetricsSummaryHandler(p))); err != nil {
+	serveMetrics(l, MetricsSummaryHandler(p))
+}
+
+func serveMetrics(l net.Listener, h http.Handler) {
+	if err := http.Serve(l, InstrumentHandler(h)); err != nil {
 		log.G(context.TODO()).WithError(err).Error("Error starting http server")
 	}
 }
 
+// probeHandler serves a probe which succeeds if ready returns true. A nil ready always succeeds.
+func probeHandler(ready func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if ready != nil && !ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
 func instrumentRequest(r *http.Request) *http.Request {
 	ctx := r.Context()
 	logger := log.G(ctx).WithFields(logrus.Fields{
@@ -0,0 +1,32 @@
+package vkubelet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers/mock"
+)
+
+func TestMetricsHandlerProfiling(t *testing.T) {
+	p, err := mock.NewMockProviderWithConfig(mock.MockConfig{}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		profiling bool
+		expected  int
+	}{
+		{false, http.StatusNotFound},
+		{true, http.StatusOK},
+	} {
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline"} {
+			w := httptest.NewRecorder()
+			metricsHandler(p, nil, c.profiling).ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != c.expected {
+				t.Errorf("Got status %d for %s with profiling %v, expected %d", w.Code, path, c.profiling, c.expected)
+			}
+		}
+	}
+}
@@ -1,63 +1,325 @@
 package vkubelet
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-// populateEnvironmentVariables populates Secrets and ConfigMap into environment variables
-func (s *Server) populateEnvironmentVariables(pod *corev1.Pod) error {
-	for _, c := range pod.Spec.Containers {
-		for i, e := range c.Env {
-			if e.ValueFrom != nil {
-				// Populate ConfigMaps to Env
-				if e.ValueFrom.ConfigMapKeyRef != nil {
-					vf := e.ValueFrom.ConfigMapKeyRef
-					cm, err := s.resourceManager.GetConfigMap(vf.Name, pod.Namespace)
-					if vf.Optional != nil && !*vf.Optional && errors.IsNotFound(err) {
-						return fmt.Errorf("ConfigMap %s is required by Pod %s and does not exist", vf.Name, pod.Name)
-					}
+// containerReasonCreateContainerConfigError is the reason of the waiting state of containers whose
+// configuration cannot be resolved.
+const containerReasonCreateContainerConfigError = "CreateContainerConfigError"
+
+// populateEnvironmentVariables populates Secrets, ConfigMap and the Downward API into environment variables
+// of the containers and init containers of the pod, and expands their envFrom sources into environment variables.
+// References to optional Secrets and ConfigMaps which do not exist are left unset.
+// Field references to the pod IP and host IP are left to the provider if they are not known yet.
+func (s *Server) populateEnvironmentVariables(ctx context.Context, pod *corev1.Pod) error {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if err := s.populateEnvFrom(ctx, pod, &containers[i]); err != nil {
+				return err
+			}
+			if err := s.populateContainerEnv(ctx, pod, &containers[i]); err != nil {
+				return err
+			}
+		}
+	}
 
-					if err != nil {
-						return fmt.Errorf("Error retrieving ConfigMap %s required by Pod %s: %s", vf.Name, pod.Name, err)
+	return nil
+}
+
+// populateContainerEnv populates the environment variables of the container whose value is taken from a source.
+func (s *Server) populateContainerEnv(ctx context.Context, pod *corev1.Pod, c *corev1.Container) error {
+	for i, e := range c.Env {
+		if e.ValueFrom != nil {
+			// Populate ConfigMaps to Env
+			if e.ValueFrom.ConfigMapKeyRef != nil {
+				vf := e.ValueFrom.ConfigMapKeyRef
+				optional := vf.Optional != nil && *vf.Optional
+				cm, err := s.resourceManager.GetConfigMap(vf.Name, pod.Namespace)
+				if errors.IsNotFound(err) {
+					if optional {
+						continue
 					}
+					return fmt.Errorf("ConfigMap %s is required by Pod %s and does not exist", vf.Name, pod.Name)
+				}
 
-					var ok bool
-					if c.Env[i].Value, ok = cm.Data[vf.Key]; !ok {
-						return fmt.Errorf("ConfigMap %s key %s is required by Pod %s and does not exist", vf.Name, vf.Key, pod.Name)
+				if err != nil {
+					return fmt.Errorf("Error retrieving ConfigMap %s required by Pod %s: %s", vf.Name, pod.Name, err)
+				}
+
+				v, ok := cm.Data[vf.Key]
+				if !ok {
+					if optional {
+						continue
 					}
-					continue
+					return fmt.Errorf("ConfigMap %s key %s is required by Pod %s and does not exist", vf.Name, vf.Key, pod.Name)
 				}
+				c.Env[i].Value = v
+				continue
+			}
 
-				// Populate Secrets to Env
-				if e.ValueFrom.SecretKeyRef != nil {
-					vf := e.ValueFrom.SecretKeyRef
-					sec, err := s.resourceManager.GetSecret(vf.Name, pod.Namespace)
-					if vf.Optional != nil && !*vf.Optional && errors.IsNotFound(err) {
-						return fmt.Errorf("Secret %s is required by Pod %s and does not exist", vf.Name, pod.Name)
+			// Populate Secrets to Env
+			if e.ValueFrom.SecretKeyRef != nil {
+				vf := e.ValueFrom.SecretKeyRef
+				optional := vf.Optional != nil && *vf.Optional
+				sec, err := s.resourceManager.GetSecret(vf.Name, pod.Namespace)
+				if errors.IsNotFound(err) {
+					if optional {
+						continue
 					}
-					v, ok := sec.Data[vf.Key]
-					if !ok {
-						return fmt.Errorf("Secret %s key %s is required by Pod %s and does not exist", vf.Name, vf.Key, pod.Name)
+					return fmt.Errorf("Secret %s is required by Pod %s and does not exist", vf.Name, pod.Name)
+				}
+
+				if err != nil {
+					return fmt.Errorf("Error retrieving Secret %s required by Pod %s: %s", vf.Name, pod.Name, err)
+				}
+
+				v, ok := sec.Data[vf.Key]
+				if !ok {
+					if optional {
+						continue
 					}
-					c.Env[i].Value = string(v)
-					continue
+					return fmt.Errorf("Secret %s key %s is required by Pod %s and does not exist", vf.Name, vf.Key, pod.Name)
+				}
+				c.Env[i].Value = string(v)
+				continue
+			}
+
+			// Populate Downward API to Env
+			if e.ValueFrom.FieldRef != nil {
+				v, err := podFieldValue(pod, e.ValueFrom.FieldRef.FieldPath)
+				if err != nil {
+					return fmt.Errorf("Invalid field reference of env %s of Pod %s: %s", e.Name, pod.Name, err)
+				}
+				if v != "" {
+					c.Env[i].Value = v
 				}
+				continue
+			}
 
-				// TODO: Populate Downward API to Env
-				if e.ValueFrom.FieldRef != nil {
-					continue
+			// Populate resource requests and limits to Env
+			if e.ValueFrom.ResourceFieldRef != nil {
+				v, err := s.containerResourceValue(ctx, pod, c, e.ValueFrom.ResourceFieldRef)
+				if err != nil {
+					return fmt.Errorf("Invalid resource field reference of env %s of Pod %s: %s", e.Name, pod.Name, err)
 				}
+				c.Env[i].Value = v
+				continue
+			}
+		}
+	}
+
+	return nil
+}
+
+// populateEnvFrom expands the envFrom sources of the container into environment variables, which are prepended
+// to those of the container so that the latter take precedence, as for the kubelet. Keys which are not valid
+// environment variable names are skipped, and reported by an event.
+func (s *Server) populateEnvFrom(ctx context.Context, pod *corev1.Pod, c *corev1.Container) error {
+	if len(c.EnvFrom) == 0 {
+		return nil
+	}
 
-				// TODO: Populate resource requests
-				if e.ValueFrom.ResourceFieldRef != nil {
+	var env []corev1.EnvVar
+	for _, from := range c.EnvFrom {
+		var kind, name string
+		var data map[string]string
+		switch {
+		case from.ConfigMapRef != nil:
+			kind, name = "ConfigMap", from.ConfigMapRef.Name
+			optional := from.ConfigMapRef.Optional != nil && *from.ConfigMapRef.Optional
+			cm, err := s.resourceManager.GetConfigMap(name, pod.Namespace)
+			if errors.IsNotFound(err) {
+				if optional {
+					continue
+				}
+				return fmt.Errorf("ConfigMap %s is required by Pod %s and does not exist", name, pod.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("Error retrieving ConfigMap %s required by Pod %s: %s", name, pod.Name, err)
+			}
+			data = cm.Data
+		case from.SecretRef != nil:
+			kind, name = "Secret", from.SecretRef.Name
+			optional := from.SecretRef.Optional != nil && *from.SecretRef.Optional
+			sec, err := s.resourceManager.GetSecret(name, pod.Namespace)
+			if errors.IsNotFound(err) {
+				if optional {
 					continue
 				}
+				return fmt.Errorf("Secret %s is required by Pod %s and does not exist", name, pod.Name)
+			}
+			if err != nil {
+				return fmt.Errorf("Error retrieving Secret %s required by Pod %s: %s", name, pod.Name, err)
+			}
+			data = make(map[string]string, len(sec.Data))
+			for k, v := range sec.Data {
+				data[k] = string(v)
+			}
+		default:
+			continue
+		}
+
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var invalid []string
+		for _, k := range keys {
+			if errs := validation.IsEnvVarName(from.Prefix + k); len(errs) != 0 {
+				invalid = append(invalid, k)
+				continue
 			}
+			env = append(env, corev1.EnvVar{Name: from.Prefix + k, Value: data[k]})
+		}
+		if len(invalid) > 0 {
+			s.recordPodEvent(ctx, pod, corev1.EventTypeWarning, eventReasonInvalidEnvironmentVariableNames, "Keys [%s] from the EnvFrom %s %s/%s were skipped since they are considered invalid environment variable names.", strings.Join(invalid, ", "), kind, pod.Namespace, name)
 		}
 	}
 
+	c.Env = append(env, c.Env...)
+	c.EnvFrom = nil
 	return nil
 }
+
+// podFieldValue returns the value of a field of the pod supported by the Downward API.
+func podFieldValue(pod *corev1.Pod, fieldPath string) (string, error) {
+	if path, key, ok := splitMapFieldPath(fieldPath); ok {
+		switch path {
+		case "metadata.labels":
+			return pod.Labels[key], nil
+		case "metadata.annotations":
+			return pod.Annotations[key], nil
+		}
+		return "", fmt.Errorf("unsupported fieldPath: %s", fieldPath)
+	}
+
+	switch fieldPath {
+	case "metadata.name":
+		return pod.Name, nil
+	case "metadata.namespace":
+		return pod.Namespace, nil
+	case "metadata.uid":
+		return string(pod.UID), nil
+	case "spec.nodeName":
+		return pod.Spec.NodeName, nil
+	case "spec.serviceAccountName":
+		return pod.Spec.ServiceAccountName, nil
+	case "status.hostIP":
+		return pod.Status.HostIP, nil
+	case "status.podIP":
+		return pod.Status.PodIP, nil
+	}
+	return "", fmt.Errorf("unsupported fieldPath: %s", fieldPath)
+}
+
+// splitMapFieldPath splits a field path such as metadata.labels['app'] into its path and key.
+func splitMapFieldPath(fieldPath string) (path, key string, ok bool) {
+	i := strings.Index(fieldPath, "['")
+	if i < 0 || !strings.HasSuffix(fieldPath, "']") {
+		return "", "", false
+	}
+	return fieldPath[:i], fieldPath[i+2 : len(fieldPath)-2], true
+}
+
+// containerResourceValue returns the value of a resource request or limit of a container, divided by the
+// divisor of the reference and rounded up. Unset limits default to the capacity of the node, as the kubelet does.
+func (s *Server) containerResourceValue(ctx context.Context, pod *corev1.Pod, c *corev1.Container, ref *corev1.ResourceFieldSelector) (string, error) {
+	if ref.ContainerName != "" && ref.ContainerName != c.Name {
+		c = nil
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].Name == ref.ContainerName {
+				c = &pod.Spec.Containers[i]
+				break
+			}
+		}
+		if c == nil {
+			return "", fmt.Errorf("container %s not found", ref.ContainerName)
+		}
+	}
+
+	parts := strings.SplitN(ref.Resource, ".", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("unsupported container resource: %s", ref.Resource)
+	}
+	name := corev1.ResourceName(parts[1])
+	if name != corev1.ResourceCPU && name != corev1.ResourceMemory && name != corev1.ResourceEphemeralStorage {
+		return "", fmt.Errorf("unsupported container resource: %s", ref.Resource)
+	}
+
+	var (
+		q   resource.Quantity
+		set bool
+	)
+	switch parts[0] {
+	case "limits":
+		q, set = c.Resources.Limits[name]
+	case "requests":
+		q, set = c.Resources.Requests[name]
+	default:
+		return "", fmt.Errorf("unsupported container resource: %s", ref.Resource)
+	}
+	if !set && parts[0] == "limits" {
+		q = s.provider.Capacity(ctx)[name]
+	}
+
+	divisor := ref.Divisor
+	if divisor.IsZero() {
+		divisor = resource.MustParse("1")
+	}
+
+	if name == corev1.ResourceCPU {
+		return fmt.Sprint(int64(math.Ceil(float64(q.MilliValue()) / float64(divisor.MilliValue())))), nil
+	}
+	return fmt.Sprint(int64(math.Ceil(float64(q.Value()) / float64(divisor.Value())))), nil
+}
+
+// waitingForConfig reports whether the creation of the pod is blocked by a Secret or ConfigMap
+// which could not be resolved.
+func waitingForConfig(pod *corev1.Pod) bool {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == containerReasonCreateContainerConfigError {
+			return true
+		}
+	}
+	return false
+}
+
+// configErrorPodStatus builds the status of a pod whose containers and init containers cannot be created
+// until the Secrets and ConfigMaps they reference can be resolved, as the kubelet reports it.
+func configErrorPodStatus(pod *corev1.Pod, err error) *corev1.PodStatus {
+	status := pod.Status.DeepCopy()
+	status.Phase = corev1.PodPending
+	status.InitContainerStatuses = configErrorContainerStatuses(pod.Spec.InitContainers, err)
+	status.ContainerStatuses = configErrorContainerStatuses(pod.Spec.Containers, err)
+
+	return status
+}
+
+func configErrorContainerStatuses(containers []corev1.Container, err error) []corev1.ContainerStatus {
+	var statuses []corev1.ContainerStatus
+	for _, c := range containers {
+		statuses = append(statuses, corev1.ContainerStatus{
+			Name:  c.Name,
+			Image: c.Image,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason:  containerReasonCreateContainerConfigError,
+					Message: err.Error(),
+				},
+			},
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,313 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodFieldValue(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			UID:         "1234",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"owner": "team-a"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:           "vk",
+			ServiceAccountName: "builder",
+		},
+		Status: corev1.PodStatus{
+			HostIP: "1.2.3.4",
+			PodIP:  "10.0.0.2",
+		},
+	}
+
+	for _, c := range []struct {
+		fieldPath   string
+		expected    string
+		expectedErr bool
+	}{
+		{fieldPath: "metadata.name", expected: "foo"},
+		{fieldPath: "metadata.namespace", expected: "default"},
+		{fieldPath: "metadata.uid", expected: "1234"},
+		{fieldPath: "metadata.labels['app']", expected: "web"},
+		{fieldPath: "metadata.labels['missing']", expected: ""},
+		{fieldPath: "metadata.annotations['owner']", expected: "team-a"},
+		{fieldPath: "spec.nodeName", expected: "vk"},
+		{fieldPath: "spec.serviceAccountName", expected: "builder"},
+		{fieldPath: "status.hostIP", expected: "1.2.3.4"},
+		{fieldPath: "status.podIP", expected: "10.0.0.2"},
+		{fieldPath: "metadata.labels", expectedErr: true},
+		{fieldPath: "spec.containers['c']", expectedErr: true},
+		{fieldPath: "status.phase", expectedErr: true},
+	} {
+		v, err := podFieldValue(pod, c.fieldPath)
+		if c.expectedErr {
+			if err == nil {
+				t.Errorf("%s: Expected an error, got %q", c.fieldPath, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", c.fieldPath, err)
+			continue
+		}
+		if v != c.expected {
+			t.Errorf("%s: Got %q, expected %q", c.fieldPath, v, c.expected)
+		}
+	}
+}
+
+func TestContainerResourceValue(t *testing.T) {
+	provider, err := mock.NewMockProviderWithConfig(mock.MockConfig{CPU: "4", Memory: "8Gi"}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{provider: provider}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("100Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("1500m"),
+						},
+					},
+				},
+				{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range []struct {
+		name        string
+		ref         corev1.ResourceFieldSelector
+		expected    string
+		expectedErr bool
+	}{
+		{name: "cpu request rounded up to a core", ref: corev1.ResourceFieldSelector{Resource: "requests.cpu"}, expected: "1"},
+		{name: "cpu request in millicores", ref: corev1.ResourceFieldSelector{Resource: "requests.cpu", Divisor: resource.MustParse("1m")}, expected: "250"},
+		{name: "cpu limit", ref: corev1.ResourceFieldSelector{Resource: "limits.cpu"}, expected: "2"},
+		{name: "memory request in bytes", ref: corev1.ResourceFieldSelector{Resource: "requests.memory"}, expected: "104857600"},
+		{name: "memory request in mebibytes", ref: corev1.ResourceFieldSelector{Resource: "requests.memory", Divisor: resource.MustParse("1Mi")}, expected: "100"},
+		{name: "memory request in megabytes", ref: corev1.ResourceFieldSelector{Resource: "requests.memory", Divisor: resource.MustParse("1M")}, expected: "105"},
+		{name: "unset memory limit defaults to capacity", ref: corev1.ResourceFieldSelector{Resource: "limits.memory", Divisor: resource.MustParse("1Gi")}, expected: "8"},
+		{name: "unset request is zero", ref: corev1.ResourceFieldSelector{Resource: "requests.ephemeral-storage"}, expected: "0"},
+		{name: "other container", ref: corev1.ResourceFieldSelector{ContainerName: "sidecar", Resource: "requests.memory", Divisor: resource.MustParse("1Mi")}, expected: "1024"},
+		{name: "missing container", ref: corev1.ResourceFieldSelector{ContainerName: "missing", Resource: "requests.cpu"}, expectedErr: true},
+		{name: "unsupported resource", ref: corev1.ResourceFieldSelector{Resource: "requests.nvidia.com/gpu"}, expectedErr: true},
+		{name: "unsupported field", ref: corev1.ResourceFieldSelector{Resource: "usage.cpu"}, expectedErr: true},
+		{name: "malformed resource", ref: corev1.ResourceFieldSelector{Resource: "cpu"}, expectedErr: true},
+	} {
+		v, err := s.containerResourceValue(context.Background(), pod, &pod.Spec.Containers[0], &c.ref)
+		if c.expectedErr {
+			if err == nil {
+				t.Errorf("%s: Expected an error, got %q", c.name, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", c.name, err)
+			continue
+		}
+		if v != c.expected {
+			t.Errorf("%s: Got %q, expected %q", c.name, v, c.expected)
+		}
+	}
+}
+
+func TestPopulateEnvironmentVariablesOptionalRefs(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}, Data: map[string]string{"key": "value"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "default"}, Data: map[string][]byte{"key": []byte("s3cr3t")}},
+	)
+	rm, err := manager.NewResourceManager(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{resourceManager: rm}
+
+	optional := true
+	configMapRef := func(name, key string, optional *bool) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key, Optional: optional,
+		}}
+	}
+	secretRef := func(name, key string, optional *bool) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key, Optional: optional,
+		}}
+	}
+
+	for _, c := range []struct {
+		name        string
+		source      *corev1.EnvVarSource
+		expected    string
+		expectedErr bool
+	}{
+		{name: "config map key", source: configMapRef("config", "key", nil), expected: "value"},
+		{name: "secret key", source: secretRef("secret", "key", nil), expected: "s3cr3t"},
+		{name: "optional missing config map", source: configMapRef("missing", "key", &optional)},
+		{name: "optional missing config map key", source: configMapRef("config", "missing", &optional)},
+		{name: "optional missing secret", source: secretRef("missing", "key", &optional)},
+		{name: "optional missing secret key", source: secretRef("secret", "missing", &optional)},
+		{name: "missing config map", source: configMapRef("missing", "key", nil), expectedErr: true},
+		{name: "missing config map key", source: configMapRef("config", "missing", nil), expectedErr: true},
+		{name: "missing secret", source: secretRef("missing", "key", nil), expectedErr: true},
+		{name: "missing secret key", source: secretRef("secret", "missing", nil), expectedErr: true},
+		{name: "field", source: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}, expected: "foo"},
+		{name: "unsupported field", source: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.phase"}}, expectedErr: true},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "c", Env: []corev1.EnvVar{{Name: "VAR", ValueFrom: c.source}}}},
+			},
+		}
+
+		err := s.populateEnvironmentVariables(context.Background(), pod)
+		if c.expectedErr {
+			if err == nil {
+				t.Errorf("%s: Expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", c.name, err)
+			continue
+		}
+		if v := pod.Spec.Containers[0].Env[0].Value; v != c.expected {
+			t.Errorf("%s: Got %q, expected %q", c.name, v, c.expected)
+		}
+	}
+}
+
+func TestPopulateEnvironmentVariablesEnvFrom(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}, Data: map[string]string{"KEY": "value", "OTHER": "other", "invalid=key": "x"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "default"}, Data: map[string][]byte{"KEY": []byte("s3cr3t")}},
+	)
+	rm, err := manager.NewResourceManager(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+	s := &Server{k8sClient: client, resourceManager: rm}
+
+	optional := true
+	configMapFrom := func(name, prefix string, optional *bool) corev1.EnvFromSource {
+		return corev1.EnvFromSource{Prefix: prefix, ConfigMapRef: &corev1.ConfigMapEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name}, Optional: optional,
+		}}
+	}
+	secretFrom := func(name, prefix string, optional *bool) corev1.EnvFromSource {
+		return corev1.EnvFromSource{Prefix: prefix, SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name}, Optional: optional,
+		}}
+	}
+
+	for _, c := range []struct {
+		name        string
+		envFrom     []corev1.EnvFromSource
+		env         []corev1.EnvVar
+		expected    map[string]string
+		expectedErr bool
+	}{
+		{
+			name:     "config map and secret",
+			envFrom:  []corev1.EnvFromSource{configMapFrom("config", "", nil), secretFrom("secret", "SECRET_", nil)},
+			expected: map[string]string{"KEY": "value", "OTHER": "other", "SECRET_KEY": "s3cr3t"},
+		},
+		{
+			name:     "env takes precedence",
+			envFrom:  []corev1.EnvFromSource{configMapFrom("config", "", nil)},
+			env:      []corev1.EnvVar{{Name: "KEY", Value: "explicit"}},
+			expected: map[string]string{"KEY": "explicit", "OTHER": "other"},
+		},
+		{
+			name:     "optional missing config map and secret",
+			envFrom:  []corev1.EnvFromSource{configMapFrom("missing", "", &optional), secretFrom("missing", "", &optional)},
+			expected: map[string]string{},
+		},
+		{name: "missing config map", envFrom: []corev1.EnvFromSource{configMapFrom("missing", "", nil)}, expectedErr: true},
+		{name: "missing secret", envFrom: []corev1.EnvFromSource{secretFrom("missing", "", nil)}, expectedErr: true},
+	} {
+		for _, init := range []bool{false, true} {
+			container := corev1.Container{Name: "c", EnvFrom: c.envFrom, Env: append([]corev1.EnvVar(nil), c.env...)}
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+			if init {
+				pod.Spec.InitContainers = []corev1.Container{container}
+			} else {
+				pod.Spec.Containers = []corev1.Container{container}
+			}
+
+			err := s.populateEnvironmentVariables(context.Background(), pod)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("%s (init container: %v): Expected an error", c.name, init)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s (init container: %v): Unexpected error: %v", c.name, init, err)
+				continue
+			}
+
+			populated := pod.Spec.Containers
+			if init {
+				populated = pod.Spec.InitContainers
+			}
+			env := make(map[string]string)
+			for _, e := range populated[0].Env {
+				env[e.Name] = e.Value
+			}
+			if !reflect.DeepEqual(env, c.expected) {
+				t.Errorf("%s (init container: %v): Got env %v, expected %v", c.name, init, env, c.expected)
+			}
+			if populated[0].EnvFrom != nil {
+				t.Errorf("%s (init container: %v): Expected envFrom to be expanded", c.name, init)
+			}
+		}
+	}
+}
+
+func TestConfigErrorPodStatus(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init"}},
+		Containers:     []corev1.Container{{Name: "c"}},
+	}}
+	status := configErrorPodStatus(pod, fmt.Errorf("ConfigMap config is required by Pod foo and does not exist"))
+
+	for _, cs := range append(status.InitContainerStatuses, status.ContainerStatuses...) {
+		if cs.State.Waiting == nil || cs.State.Waiting.Reason != containerReasonCreateContainerConfigError {
+			t.Errorf("Got state %+v for container %s, expected waiting with %s", cs.State, cs.Name, containerReasonCreateContainerConfigError)
+		}
+	}
+	if len(status.InitContainerStatuses) != 1 || len(status.ContainerStatuses) != 1 {
+		t.Errorf("Got %d init container and %d container statuses, expected one of each", len(status.InitContainerStatuses), len(status.ContainerStatuses))
+	}
+}
@@ -0,0 +1,106 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// eventSourceComponent is the component reported as the source of the events.
+	eventSourceComponent = "virtual-kubelet"
+
+	// Reasons of the events recorded for pods, which match the ones of the kubelet.
+	eventReasonPulling          = "Pulling"
+	eventReasonStarted          = "Started"
+	eventReasonKilling          = "Killing"
+	eventReasonOOMKilled        = "OOMKilled"
+	eventReasonProviderFailed   = "ProviderFailed"
+	eventReasonFailed           = "Failed"
+	eventReasonCapacityExceeded = "CapacityExceeded"
+
+	eventReasonInvalidEnvironmentVariableNames = "InvalidEnvironmentVariableNames"
+)
+
+// recordPodEvent creates an event involving the pod within Kubernetes.
+// Failures are logged but not returned, as events are informational only.
+func (s *Server) recordPodEvent(ctx context.Context, pod *corev1.Pod, eventType, reason, messageFmt string, args ...interface{}) {
+	now := metav1.NewTime(time.Now())
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", pod.Name, now.UnixNano()),
+			Namespace: pod.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:            "Pod",
+			APIVersion:      "v1",
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Reason:  reason,
+		Message: fmt.Sprintf(messageFmt, args...),
+		Source: corev1.EventSource{
+			Component: eventSourceComponent,
+			Host:      s.nodeName,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+
+	if _, err := s.k8sClient.CoreV1().Events(pod.Namespace).Create(event); err != nil {
+		log.G(ctx).WithError(err).WithField("reason", reason).Warn("Failed to record pod event")
+	}
+}
+
+// recordContainerEvents records events for the transitions of the pod's containers between two statuses.
+// Providers do not report image pulls, so a container is reported pulling its image when it first appears.
+func (s *Server) recordContainerEvents(ctx context.Context, pod *corev1.Pod, oldStatus, newStatus *corev1.PodStatus) {
+	old := make(map[string]corev1.ContainerState, len(oldStatus.ContainerStatuses))
+	for _, cs := range oldStatus.ContainerStatuses {
+		old[cs.Name] = cs.State
+	}
+
+	for _, cs := range newStatus.ContainerStatuses {
+		prev, seen := old[cs.Name]
+		if !seen && (cs.State.Waiting != nil || cs.State.Running != nil) {
+			s.recordPodEvent(ctx, pod, corev1.EventTypeNormal, eventReasonPulling, "Pulling image %q", cs.Image)
+		}
+		switch {
+		case cs.State.Running != nil && prev.Running == nil:
+			s.recordPodEvent(ctx, pod, corev1.EventTypeNormal, eventReasonStarted, "Started container %s", cs.Name)
+		case cs.State.Terminated != nil && prev.Terminated == nil && cs.State.Terminated.Reason == eventReasonOOMKilled:
+			s.recordPodEvent(ctx, pod, corev1.EventTypeWarning, eventReasonOOMKilled, "Container %s was OOM killed", cs.Name)
+		}
+	}
+}
+
+// recordRejectionEvent records an event if the new status reports the pod rejected, i.e. failed without having
+// been started. Pods rejected for lack of resources, including the exclusive cores of the CPU manager, are
+// reported with the CapacityExceeded reason, the others with the reason of their rejection.
+func (s *Server) recordRejectionEvent(ctx context.Context, pod *corev1.Pod, oldStatus, newStatus *corev1.PodStatus) {
+	if oldStatus.Phase == corev1.PodFailed || newStatus.Phase != corev1.PodFailed || newStatus.StartTime != nil {
+		return
+	}
+
+	reason := newStatus.Reason
+	switch {
+	case reason == providers.PodReasonExceededQuota || strings.HasPrefix(reason, providers.PodReasonOutOfPrefix):
+		reason = eventReasonCapacityExceeded
+	case reason == providers.PodReasonUnexpectedAdmissionError && strings.Contains(newStatus.Message, providers.PodMessageNotEnoughCPUs):
+		reason = eventReasonCapacityExceeded
+	case reason == "":
+		reason = eventReasonFailed
+	}
+	s.recordPodEvent(ctx, pod, corev1.EventTypeWarning, reason, "%s", newStatus.Message)
+}
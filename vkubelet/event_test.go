@@ -0,0 +1,111 @@
+package vkubelet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func eventReasons(t *testing.T, s *Server) map[string]int {
+	events, err := s.k8sClient.CoreV1().Events("default").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(map[string]int)
+	for _, e := range events.Items {
+		reasons[e.Reason]++
+	}
+	return reasons
+}
+
+func TestRecordContainerEvents(t *testing.T) {
+	s := &Server{k8sClient: fake.NewSimpleClientset(), nodeName: "vk"}
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+
+	waiting := corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+		{Name: "c", Image: "nginx", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+	}}
+	running := corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+		{Name: "c", Image: "nginx", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+	}}
+	s.recordContainerEvents(ctx, pod, &corev1.PodStatus{}, &waiting)
+	s.recordContainerEvents(ctx, pod, &waiting, &running)
+	s.recordContainerEvents(ctx, pod, &running, &running)
+
+	reasons := eventReasons(t, s)
+	if reasons[eventReasonPulling] != 1 || reasons[eventReasonStarted] != 1 || len(reasons) != 2 {
+		t.Errorf("Got events %v, expected one %s and one %s event", reasons, eventReasonPulling, eventReasonStarted)
+	}
+}
+
+func TestRecordRejectionEvent(t *testing.T) {
+	s := &Server{k8sClient: fake.NewSimpleClientset(), nodeName: "vk"}
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	pending := &corev1.PodStatus{Phase: corev1.PodPending}
+
+	for _, rejected := range []corev1.PodStatus{
+		{Reason: "OutOfcpu"},
+		{Reason: providers.PodReasonExceededQuota},
+		{Reason: providers.PodReasonUnexpectedAdmissionError, Message: "Pod was rejected: " + providers.PodMessageNotEnoughCPUs + ": requested 4, available 1"},
+		{Reason: providers.PodReasonUnexpectedAdmissionError, Message: "Pod was rejected: pod has no containers"},
+		{Reason: "PodFitsHostPorts"},
+	} {
+		rejected.Phase = corev1.PodFailed
+		s.recordRejectionEvent(ctx, pod, pending, &rejected)
+	}
+	started := metav1.Now()
+	s.recordRejectionEvent(ctx, pod, pending, &corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Error", StartTime: &started})
+
+	reasons := eventReasons(t, s)
+	if reasons[eventReasonCapacityExceeded] != 3 || reasons[providers.PodReasonUnexpectedAdmissionError] != 1 || reasons["PodFitsHostPorts"] != 1 || len(reasons) != 3 {
+		t.Errorf("Got events %v, expected three %s events, one %s event and one PodFitsHostPorts event", reasons, eventReasonCapacityExceeded, providers.PodReasonUnexpectedAdmissionError)
+	}
+}
+
+func TestKillingEventRecordedOnce(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rm, err := manager.NewResourceManager(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+	graceful := true
+	p, err := mock.NewMockProviderWithConfig(mock.MockConfig{GracefulDeletion: &graceful}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{k8sClient: client, nodeName: "vk", provider: p, resourceManager: rm, deleting: make(map[string]bool)}
+	ctx := context.Background()
+
+	grace := int64(30)
+	now := metav1.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "vk", Containers: []corev1.Container{{Name: "c", Image: "nginx"}}},
+	}
+	if err := p.CreatePod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.DeletionTimestamp = &now
+	pod.DeletionGracePeriodSeconds = &grace
+
+	// The deletion is retried while the pod terminates in the provider.
+	for i := 0; i < 3; i++ {
+		if err := s.deletePod(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reasons := eventReasons(t, s)
+	if reasons[eventReasonKilling] != 1 {
+		t.Errorf("Got %d %s events, expected 1", reasons[eventReasonKilling], eventReasonKilling)
+	}
+}
@@ -0,0 +1,94 @@
+package vkubelet
+
+import (
+	"context"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"go.opencensus.io/trace"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeLeaseNamespace is the namespace holding the leases of the nodes.
+const nodeLeaseNamespace = "kube-node-lease"
+
+// runNodeLease renews the lease of the node every quarter of the lease duration, as the kubelet does,
+// until the context is cancelled or Stop is called.
+func (s *Server) runNodeLease(ctx context.Context) {
+	defer s.syncLoops.Done()
+
+	ticker := time.NewTicker(time.Duration(s.nodeLeaseDurationSeconds) * time.Second / 4)
+	defer ticker.Stop()
+
+	for {
+		s.renewNodeLease(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewNodeLease creates or renews the lease of the node within Kubernetes.
+// Providers implementing providers.NodeLeaseProvider can pause the renewal to simulate a loss of heartbeats.
+func (s *Server) renewNodeLease(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "renewNodeLease")
+	defer span.End()
+
+	if lp, ok := s.provider.(providers.NodeLeaseProvider); ok && !lp.ShouldRenewNodeLease(ctx) {
+		span.Annotate(nil, "Node lease renewal paused by the provider")
+		return
+	}
+
+	leases := s.k8sClient.CoordinationV1beta1().Leases(nodeLeaseNamespace)
+	now := metav1.NewMicroTime(time.Now())
+
+	lease, err := leases.Get(s.nodeName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		lease = &coordinationv1beta1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.nodeName,
+				Namespace: nodeLeaseNamespace,
+			},
+		}
+		s.setNodeLeaseSpec(lease, now)
+		if node, err := s.k8sClient.CoreV1().Nodes().Get(s.nodeName, metav1.GetOptions{}); err == nil {
+			lease.OwnerReferences = []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       node.Name,
+					UID:        node.UID,
+				},
+			}
+		}
+		_, err = leases.Create(lease)
+	case err == nil:
+		s.setNodeLeaseSpec(lease, now)
+		_, err = leases.Update(lease)
+	}
+
+	if err != nil {
+		log.G(ctx).WithError(err).Error("Failed to renew node lease")
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+		return
+	}
+	span.Annotate(nil, "Renewed node lease")
+}
+
+func (s *Server) setNodeLeaseSpec(lease *coordinationv1beta1.Lease, now metav1.MicroTime) {
+	holder := s.nodeName
+	duration := s.nodeLeaseDurationSeconds
+
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+}
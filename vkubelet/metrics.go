@@ -0,0 +1,583 @@
+package vkubelet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// mPodBindingLatency is the time between a pod being bound to the node and the pod being passed to the provider.
+	mPodBindingLatency = stats.Float64("virtual_kubelet/pod_binding_latency", "Time between a pod being bound to the node and CreatePod being called on the provider", stats.UnitMilliseconds)
+
+	// mPodCreationLatency is the time between a pod being created and the pod being passed to the provider.
+	mPodCreationLatency = stats.Float64("virtual_kubelet/pod_creation_latency", "Time between a pod being created and CreatePod being called on the provider", stats.UnitMilliseconds)
+
+	// mPodStartupLatency is the time between a pod being created and the provider reporting it running.
+	mPodStartupLatency = stats.Float64("virtual_kubelet/pod_startup_latency", "Time between a pod being created and the provider reporting it running", stats.UnitMilliseconds)
+
+	// mProviderLatency is the time taken by the provider to handle an operation.
+	mProviderLatency = stats.Float64("virtual_kubelet/provider_latency", "Time taken by the provider to handle an operation", stats.UnitMilliseconds)
+
+	// mSyncLoopDuration is the time taken by an iteration of a sync loop.
+	mSyncLoopDuration = stats.Float64("virtual_kubelet/sync_loop_duration", "Time taken by an iteration of a sync loop", stats.UnitMilliseconds)
+
+	// keyOperation is the provider operation the provider latency is recorded for.
+	keyOperation, _ = tag.NewKey("operation")
+
+	// keySyncLoop is the sync loop the sync loop duration is recorded for.
+	keySyncLoop, _ = tag.NewKey("loop")
+
+	// latencyBuckets are the histogram bucket boundaries, in milliseconds, used by latency views.
+	latencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+)
+
+var (
+	// PodBindingLatencyView is a histogram of the time between a pod being bound to the node and the
+	// provider being asked to create it.
+	// It isolates the propagation latency of the virtual kubelet from the latency of the scheduler.
+	PodBindingLatencyView = &view.View{
+		Name:        "virtual_kubelet/pod_binding_latency",
+		Description: "Time between a pod being bound to the node and CreatePod being called on the provider",
+		Measure:     mPodBindingLatency,
+		Aggregation: view.Distribution(latencyBuckets...),
+	}
+
+	// PodCreationLatencyView is a histogram of the time between a pod being created and the provider
+	// being asked to create it, which includes the scheduling latency.
+	PodCreationLatencyView = &view.View{
+		Name:        "virtual_kubelet/pod_creation_latency",
+		Description: "Time between a pod being created and CreatePod being called on the provider",
+		Measure:     mPodCreationLatency,
+		Aggregation: view.Distribution(latencyBuckets...),
+	}
+
+	// PodStartupLatencyView is a histogram of the time between a pod being created and the provider
+	// reporting it running, which includes the startup latency of the provider.
+	PodStartupLatencyView = &view.View{
+		Name:        "virtual_kubelet/pod_startup_latency",
+		Description: "Time between a pod being created and the provider reporting it running",
+		Measure:     mPodStartupLatency,
+		Aggregation: view.Distribution(latencyBuckets...),
+	}
+
+	// ProviderLatencyView is a histogram of the time taken by the provider to handle each operation.
+	// It tells whether the provider becomes the bottleneck of the virtual kubelet.
+	ProviderLatencyView = &view.View{
+		Name:        "virtual_kubelet/provider_latency",
+		Description: "Time taken by the provider to handle an operation",
+		Measure:     mProviderLatency,
+		TagKeys:     []tag.Key{keyOperation},
+		Aggregation: view.Distribution(latencyBuckets...),
+	}
+
+	// SyncLoopDurationView is a histogram of the time taken by each iteration of the node status
+	// and pod status sync loops. Iterations taking longer than the sync interval delay the next ones.
+	SyncLoopDurationView = &view.View{
+		Name:        "virtual_kubelet/sync_loop_duration",
+		Description: "Time taken by an iteration of a sync loop",
+		Measure:     mSyncLoopDuration,
+		TagKeys:     []tag.Key{keySyncLoop},
+		Aggregation: view.Distribution(latencyBuckets...),
+	}
+
+	// latencyViews are the views served by LatencyHandler.
+	latencyViews = []*view.View{PodBindingLatencyView, PodCreationLatencyView, PodStartupLatencyView, ProviderLatencyView, SyncLoopDurationView}
+)
+
+var (
+	// keyNamespace is the namespace of the pods counted by the pod lifecycle metrics.
+	keyNamespace, _ = tag.NewKey("namespace")
+
+	// keyNode is the node the pod gauges are recorded by, so that the nodes run by the same process
+	// don't overwrite each other's gauges.
+	keyNode, _ = tag.NewKey("node")
+
+	mPodsCreated   = stats.Int64("virtual_kubelet/pods_created", "Number of pods created in the provider", stats.UnitDimensionless)
+	mPodsSucceeded = stats.Int64("virtual_kubelet/pods_succeeded", "Number of pods which succeeded", stats.UnitDimensionless)
+	mPodsFailed    = stats.Int64("virtual_kubelet/pods_failed", "Number of pods which failed after being started", stats.UnitDimensionless)
+	mPodsRejected  = stats.Int64("virtual_kubelet/pods_rejected", "Number of pods which failed before being started", stats.UnitDimensionless)
+	mPodsPreempted = stats.Int64("virtual_kubelet/pods_preempted", "Number of pods preempted to make room for critical pods", stats.UnitDimensionless)
+	mPodsEvicted   = stats.Int64("virtual_kubelet/pods_evicted", "Number of pods evicted from the node", stats.UnitDimensionless)
+	mPodsRunning   = stats.Int64("virtual_kubelet/pods_running", "Number of running pods", stats.UnitDimensionless)
+	mPodsWaiting   = stats.Int64("virtual_kubelet/pods_waiting", "Number of pods waiting for their Secrets and ConfigMaps", stats.UnitDimensionless)
+)
+
+var (
+	// PodsCreatedView counts the pods created in the provider, by namespace.
+	PodsCreatedView = newPodCountView(mPodsCreated, view.Count())
+
+	// PodsSucceededView counts the pods which succeeded, by namespace.
+	PodsSucceededView = newPodCountView(mPodsSucceeded, view.Count())
+
+	// PodsFailedView counts the pods which failed after being started, by namespace.
+	PodsFailedView = newPodCountView(mPodsFailed, view.Count())
+
+	// PodsRejectedView counts the pods which failed before being started, by namespace.
+	// These are the pods rejected by the provider, e.g. for lack of capacity.
+	PodsRejectedView = newPodCountView(mPodsRejected, view.Count())
+
+	// PodsPreemptedView counts the pods preempted to make room for critical pods, by namespace.
+	// They are not counted as failed.
+	PodsPreemptedView = newPodCountView(mPodsPreempted, view.Count())
+
+	// PodsEvictedView counts the pods evicted from the node, e.g. when it is low on a resource, by namespace.
+	// They are not counted as failed.
+	PodsEvictedView = newPodCountView(mPodsEvicted, view.Count())
+
+	// PodsRunningView is the number of running pods, by namespace and node.
+	PodsRunningView = newPodCountView(mPodsRunning, view.LastValue(), keyNode)
+
+	// PodsWaitingView is the number of pods which are not created in the provider yet because
+	// they wait for their Secrets and ConfigMaps, by namespace and node.
+	PodsWaitingView = newPodCountView(mPodsWaiting, view.LastValue(), keyNode)
+
+	// lifecycleViews are the views served by PodLifecycleHandler.
+	lifecycleViews = []*view.View{PodsCreatedView, PodsSucceededView, PodsFailedView, PodsRejectedView, PodsPreemptedView, PodsEvictedView, PodsRunningView, PodsWaitingView}
+)
+
+var (
+	// keyResource is the resource the namespace resource gauges are recorded for.
+	keyResource, _ = tag.NewKey("resource")
+
+	mNamespaceRequested = stats.Float64("virtual_kubelet/namespace_requested", "Resources requested by the pods of the namespace which are not terminated", stats.UnitDimensionless)
+	mNamespaceUsed      = stats.Float64("virtual_kubelet/namespace_used", "Resources used by the pods of the namespace, as reported by the provider", stats.UnitDimensionless)
+)
+
+var (
+	// NamespaceRequestedView is the amount of each resource requested by the pods of the node which are
+	// not terminated, including those waiting or pending, by namespace and node. CPUs are counted in cores.
+	NamespaceRequestedView = newNamespaceResourceView(mNamespaceRequested)
+
+	// NamespaceUsedView is the amount of each resource used by the pods of the node, by namespace and node,
+	// for providers implementing providers.NamespaceUsageProvider. Compared to the requested resources, it
+	// tells the share of its demand each namespace is granted.
+	NamespaceUsedView = newNamespaceResourceView(mNamespaceUsed)
+
+	// resourceViews are the views served by NamespaceResourcesHandler.
+	resourceViews = []*view.View{NamespaceRequestedView, NamespaceUsedView}
+)
+
+func newPodCountView(m *stats.Int64Measure, agg *view.Aggregation, keys ...tag.Key) *view.View {
+	return &view.View{
+		Name:        m.Name(),
+		Description: m.Description(),
+		Measure:     m,
+		TagKeys:     append([]tag.Key{keyNamespace}, keys...),
+		Aggregation: agg,
+	}
+}
+
+func newNamespaceResourceView(m *stats.Float64Measure) *view.View {
+	return &view.View{
+		Name:        m.Name(),
+		Description: m.Description(),
+		Measure:     m,
+		TagKeys:     []tag.Key{keyNamespace, keyNode, keyResource},
+		Aggregation: view.LastValue(),
+	}
+}
+
+// registerViews registers the views of the metrics recorded by the virtual kubelet.
+func registerViews() error {
+	if err := view.Register(latencyViews...); err != nil {
+		return err
+	}
+	if err := view.Register(lifecycleViews...); err != nil {
+		return err
+	}
+	return view.Register(resourceViews...)
+}
+
+// podBindingTime returns the time the pod was bound to a node, i.e. the last transition time of
+// its PodScheduled condition. It returns false if the pod has not been bound yet.
+func podBindingTime(pod *corev1.Pod) (time.Time, bool) {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time, !c.LastTransitionTime.IsZero()
+		}
+	}
+	return time.Time{}, false
+}
+
+// recordPodBindingLatency records the time elapsed since the pod was bound to the node.
+// Only pods which have not been started yet are measured so that resyncs of running pods don't skew the histogram.
+func recordPodBindingLatency(ctx context.Context, pod *corev1.Pod) {
+	if pod.Status.StartTime != nil {
+		return
+	}
+	bound, ok := podBindingTime(pod)
+	if !ok {
+		return
+	}
+	stats.Record(ctx, mPodBindingLatency.M(sinceInMilliseconds(bound)))
+}
+
+// recordPodCreationLatency records the time elapsed since the pod was created.
+// Like the binding latency, only pods which have not been started yet are measured.
+func recordPodCreationLatency(ctx context.Context, pod *corev1.Pod) {
+	if pod.Status.StartTime != nil || pod.CreationTimestamp.IsZero() {
+		return
+	}
+	stats.Record(ctx, mPodCreationLatency.M(sinceInMilliseconds(pod.CreationTimestamp.Time)))
+}
+
+// recordPodStartupLatency records the time elapsed since the pod was created, if the new status
+// reports it running for the first time.
+func recordPodStartupLatency(ctx context.Context, pod *corev1.Pod, oldStatus, newStatus *corev1.PodStatus) {
+	if oldStatus.Phase == corev1.PodRunning || newStatus.Phase != corev1.PodRunning || pod.CreationTimestamp.IsZero() {
+		return
+	}
+	stats.Record(ctx, mPodStartupLatency.M(sinceInMilliseconds(pod.CreationTimestamp.Time)))
+}
+
+// recordProviderLatency records the time elapsed since the provider was asked to handle the operation.
+func recordProviderLatency(ctx context.Context, operation string, start time.Time) {
+	recordTaggedLatency(ctx, keyOperation, operation, mProviderLatency, start)
+}
+
+// recordSyncLoopDuration records the time elapsed since the iteration of the sync loop started.
+func recordSyncLoopDuration(ctx context.Context, loop string, start time.Time) {
+	recordTaggedLatency(ctx, keySyncLoop, loop, mSyncLoopDuration, start)
+}
+
+func recordTaggedLatency(ctx context.Context, key tag.Key, value string, m *stats.Float64Measure, start time.Time) {
+	ctx, err := tag.New(ctx, tag.Upsert(key, value))
+	if err != nil {
+		log.G(ctx).WithError(err).Errorf("Error tagging %s", m.Name())
+		return
+	}
+	stats.Record(ctx, m.M(sinceInMilliseconds(start)))
+}
+
+func sinceInMilliseconds(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+// latencySummary summarizes a latency view, in milliseconds.
+// Percentiles are estimated from the histogram buckets, which are served as well: Counts holds the number of
+// samples below each of the Bounds, then above the last one.
+type latencySummary struct {
+	Count  int64     `json:"count"`
+	Mean   float64   `json:"mean"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	P50    float64   `json:"p50"`
+	P90    float64   `json:"p90"`
+	P99    float64   `json:"p99"`
+	Bounds []float64 `json:"bounds"`
+	Counts []int64   `json:"counts"`
+}
+
+func summarizeLatency(d *view.DistributionData) latencySummary {
+	return latencySummary{
+		Count:  d.Count,
+		Mean:   d.Mean,
+		Min:    d.Min,
+		Max:    d.Max,
+		P50:    estimatePercentile(d, 0.5),
+		P90:    estimatePercentile(d, 0.9),
+		P99:    estimatePercentile(d, 0.99),
+		Bounds: latencyBuckets,
+		Counts: d.CountPerBucket,
+	}
+}
+
+// estimatePercentile estimates a percentile of a distribution bucketed by latencyBuckets,
+// interpolating linearly within the bucket containing it.
+func estimatePercentile(d *view.DistributionData, p float64) float64 {
+	if d.Count == 0 {
+		return 0
+	}
+
+	rank := p * float64(d.Count)
+	var cumulative float64
+	for i, n := range d.CountPerBucket {
+		if n == 0 || cumulative+float64(n) < rank {
+			cumulative += float64(n)
+			continue
+		}
+
+		lower, upper := d.Min, d.Max
+		if i > 0 && latencyBuckets[i-1] > lower {
+			lower = latencyBuckets[i-1]
+		}
+		if i < len(latencyBuckets) && latencyBuckets[i] < upper {
+			upper = latencyBuckets[i]
+		}
+		return lower + (upper-lower)*(rank-cumulative)/float64(n)
+	}
+	return d.Max
+}
+
+// LatencyHandler serves a JSON summary, with the histogram, of the latency views recorded by the virtual kubelet,
+// keyed by view name. Views tagged by operation or sync loop are keyed by view name and tag value,
+// e.g. virtual_kubelet/provider_latency/CreatePod.
+func LatencyHandler(w http.ResponseWriter, req *http.Request) {
+	summaries := make(map[string]latencySummary, len(latencyViews))
+	for _, v := range latencyViews {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(v.TagKeys) == 0 {
+			summaries[v.Name] = latencySummary{Bounds: latencyBuckets, Counts: make([]int64, len(latencyBuckets)+1)}
+		}
+		for _, row := range rows {
+			d, ok := row.Data.(*view.DistributionData)
+			if !ok {
+				continue
+			}
+			name := v.Name
+			for _, t := range row.Tags {
+				name += "/" + t.Value
+			}
+			summaries[name] = summarizeLatency(d)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summaries); err != nil {
+		log.G(req.Context()).WithError(err).Error("Error writing latency summary")
+	}
+}
+
+// recordPodCount records a pod lifecycle measurement for the namespace.
+func recordPodCount(ctx context.Context, namespace string, m stats.Measurement) {
+	recordPodMeasurement(ctx, m, tag.Upsert(keyNamespace, namespace))
+}
+
+// recordPodGauge records a pod gauge measurement for the namespace and the node.
+func recordPodGauge(ctx context.Context, namespace, node string, m stats.Measurement) {
+	recordPodMeasurement(ctx, m, tag.Upsert(keyNamespace, namespace), tag.Upsert(keyNode, node))
+}
+
+func recordPodMeasurement(ctx context.Context, m stats.Measurement, mutators ...tag.Mutator) {
+	ctx, err := tag.New(ctx, mutators...)
+	if err != nil {
+		log.G(ctx).WithError(err).Error("Error tagging pod lifecycle metric")
+		return
+	}
+	stats.Record(ctx, m)
+}
+
+// recordPodCreated counts the pod as created in the provider.
+// Like the creation latency, only pods which have not been started yet are counted.
+func recordPodCreated(ctx context.Context, pod *corev1.Pod) {
+	if pod.Status.StartTime != nil {
+		return
+	}
+	recordPodCount(ctx, pod.Namespace, mPodsCreated.M(1))
+}
+
+// recordPodCompletion counts the pod as succeeded, failed, rejected, preempted or evicted if the new status
+// reports it in one of the terminal phases for the first time. A pod which failed without ever being started
+// is counted as rejected, and one which failed for being preempted or evicted is counted as such.
+func recordPodCompletion(ctx context.Context, pod *corev1.Pod, oldStatus, newStatus *corev1.PodStatus) {
+	if oldStatus.Phase == newStatus.Phase {
+		return
+	}
+	switch {
+	case newStatus.Phase == corev1.PodSucceeded:
+		recordPodCount(ctx, pod.Namespace, mPodsSucceeded.M(1))
+	case newStatus.Phase == corev1.PodFailed && newStatus.StartTime == nil:
+		recordPodCount(ctx, pod.Namespace, mPodsRejected.M(1))
+	case newStatus.Phase == corev1.PodFailed && newStatus.Reason == providers.PodReasonPreempting:
+		recordPodCount(ctx, pod.Namespace, mPodsPreempted.M(1))
+	case newStatus.Phase == corev1.PodFailed && newStatus.Reason == providers.PodReasonEvicted:
+		recordPodCount(ctx, pod.Namespace, mPodsEvicted.M(1))
+	case newStatus.Phase == corev1.PodFailed:
+		recordPodCount(ctx, pod.Namespace, mPodsFailed.M(1))
+	}
+}
+
+// recordPodGauges records the number of running and waiting pods of each namespace.
+// Namespaces which had pods the last time are reset to zero once they have none.
+func (s *Server) recordPodGauges(ctx context.Context, pods []*corev1.Pod) {
+	running := make(map[string]int64)
+	waiting := make(map[string]int64)
+	namespaces := make(map[string]bool)
+	for _, pod := range pods {
+		namespaces[pod.Namespace] = true
+		if waitingForConfig(pod) {
+			waiting[pod.Namespace]++
+		} else if pod.Status.Phase == corev1.PodRunning {
+			running[pod.Namespace]++
+		}
+	}
+
+	for ns := range s.gaugeNamespaces {
+		if !namespaces[ns] {
+			recordPodGauge(ctx, ns, s.nodeName, mPodsRunning.M(0))
+			recordPodGauge(ctx, ns, s.nodeName, mPodsWaiting.M(0))
+		}
+	}
+	for ns := range namespaces {
+		recordPodGauge(ctx, ns, s.nodeName, mPodsRunning.M(running[ns]))
+		recordPodGauge(ctx, ns, s.nodeName, mPodsWaiting.M(waiting[ns]))
+	}
+	s.gaugeNamespaces = namespaces
+}
+
+// PodLifecycleHandler serves a JSON summary of the pod lifecycle views recorded by the virtual kubelet,
+// keyed by view name and then by namespace. The gauges of the nodes run by the process are summed up.
+func PodLifecycleHandler(w http.ResponseWriter, req *http.Request) {
+	counts := make(map[string]map[string]int64, len(lifecycleViews))
+	for _, v := range lifecycleViews {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		byNamespace := make(map[string]int64)
+		for _, row := range rows {
+			var namespace string
+			for _, t := range row.Tags {
+				if t.Key == keyNamespace {
+					namespace = t.Value
+				}
+			}
+
+			switch d := row.Data.(type) {
+			case *view.CountData:
+				byNamespace[namespace] += d.Value
+			case *view.LastValueData:
+				byNamespace[namespace] += int64(d.Value)
+			}
+		}
+		counts[v.Name] = byNamespace
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(counts); err != nil {
+		log.G(req.Context()).WithError(err).Error("Error writing pod lifecycle summary")
+	}
+}
+
+// resourceGauge identifies a namespace resource gauge recorded by a node.
+type resourceGauge struct {
+	namespace string
+	resource  corev1.ResourceName
+}
+
+// podRequests returns the resources requested by the pod: the sum of the requests of its containers, or the
+// largest request of its init containers if it is larger, as for the scheduler.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if current, ok := requests[name]; !ok || q.Cmp(current) > 0 {
+				requests[name] = q.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// recordNamespaceResources records the resources requested by the pods of each namespace which are not
+// terminated, and those used by the pods of each namespace if the provider reports them.
+func (s *Server) recordNamespaceResources(ctx context.Context, pods []*corev1.Pod) {
+	requested := make(map[string]corev1.ResourceList)
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		sum, ok := requested[pod.Namespace]
+		if !ok {
+			sum = corev1.ResourceList{}
+			requested[pod.Namespace] = sum
+		}
+		for name, q := range podRequests(pod) {
+			total := sum[name]
+			total.Add(q)
+			sum[name] = total
+		}
+	}
+	s.requestedGauges = s.recordResourceGauges(ctx, mNamespaceRequested, requested, s.requestedGauges)
+
+	if up, ok := s.provider.(providers.NamespaceUsageProvider); ok {
+		s.usedGauges = s.recordResourceGauges(ctx, mNamespaceUsed, up.NamespaceUsage(ctx), s.usedGauges)
+	}
+}
+
+// recordResourceGauges records the resources of each namespace as gauges of m, and resets to zero the gauges
+// recorded the last time which are no longer reported. It returns the gauges recorded.
+func (s *Server) recordResourceGauges(ctx context.Context, m *stats.Float64Measure, byNamespace map[string]corev1.ResourceList, last map[resourceGauge]bool) map[resourceGauge]bool {
+	recorded := make(map[resourceGauge]bool)
+	for namespace, resources := range byNamespace {
+		for name, q := range resources {
+			recorded[resourceGauge{namespace, name}] = true
+			recordResourceGauge(ctx, namespace, s.nodeName, name, m.M(float64(q.MilliValue())/1000))
+		}
+	}
+	for g := range last {
+		if !recorded[g] {
+			recordResourceGauge(ctx, g.namespace, s.nodeName, g.resource, m.M(0))
+		}
+	}
+	return recorded
+}
+
+// recordResourceGauge records a namespace resource gauge measurement for the namespace, the node and the resource.
+func recordResourceGauge(ctx context.Context, namespace, node string, resource corev1.ResourceName, m stats.Measurement) {
+	recordPodMeasurement(ctx, m, tag.Upsert(keyNamespace, namespace), tag.Upsert(keyNode, node), tag.Upsert(keyResource, string(resource)))
+}
+
+// NamespaceResourcesHandler serves a JSON summary of the namespace resource views recorded by the virtual
+// kubelet, keyed by view name, then by namespace and then by resource. The gauges of the nodes run by the
+// process are summed up.
+func NamespaceResourcesHandler(w http.ResponseWriter, req *http.Request) {
+	summary := make(map[string]map[string]map[string]float64, len(resourceViews))
+	for _, v := range resourceViews {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		byNamespace := make(map[string]map[string]float64)
+		for _, row := range rows {
+			d, ok := row.Data.(*view.LastValueData)
+			if !ok {
+				continue
+			}
+			var namespace, resource string
+			for _, t := range row.Tags {
+				switch t.Key {
+				case keyNamespace:
+					namespace = t.Value
+				case keyResource:
+					resource = t.Value
+				}
+			}
+			if byNamespace[namespace] == nil {
+				byNamespace[namespace] = make(map[string]float64)
+			}
+			byNamespace[namespace][resource] += d.Value
+		}
+		summary[v.Name] = byNamespace
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.G(req.Context()).WithError(err).Error("Error writing namespace resources summary")
+	}
+}
@@ -0,0 +1,220 @@
+package vkubelet
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/mock"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEstimatePercentile(t *testing.T) {
+	// countPerBucket returns the bucket counts of latencyBuckets with the given counts set by bucket index.
+	countPerBucket := func(counts map[int]int64) []int64 {
+		c := make([]int64, len(latencyBuckets)+1)
+		for i, n := range counts {
+			c[i] = n
+		}
+		return c
+	}
+
+	for _, c := range []struct {
+		name     string
+		data     *view.DistributionData
+		p        float64
+		expected float64
+	}{
+		{
+			name:     "empty distribution",
+			data:     &view.DistributionData{CountPerBucket: countPerBucket(nil)},
+			p:        0.5,
+			expected: 0,
+		},
+		{
+			name:     "single sample",
+			data:     &view.DistributionData{Count: 1, Min: 42, Max: 42, CountPerBucket: countPerBucket(map[int]int64{4: 1})},
+			p:        0.99,
+			expected: 42,
+		},
+		{
+			// 10 samples in [10, 25): the median is halfway through the bucket.
+			name:     "interpolated within a bucket",
+			data:     &view.DistributionData{Count: 10, Min: 10, Max: 24, CountPerBucket: countPerBucket(map[int]int64{3: 10})},
+			p:        0.5,
+			expected: 17,
+		},
+		{
+			// 50 samples in [1, 5) and 50 in [100, 250): the 90th percentile is 80% through the upper bucket.
+			name:     "interpolated within a later bucket",
+			data:     &view.DistributionData{Count: 100, Min: 2, Max: 250, CountPerBucket: countPerBucket(map[int]int64{1: 50, 6: 50})},
+			p:        0.9,
+			expected: 220,
+		},
+		{
+			name:     "lower bucket bounded by the minimum",
+			data:     &view.DistributionData{Count: 4, Min: 3, Max: 4, CountPerBucket: countPerBucket(map[int]int64{1: 4})},
+			p:        0.5,
+			expected: 3.5,
+		},
+		{
+			name:     "overflow bucket bounded by the maximum",
+			data:     &view.DistributionData{Count: 2, Min: 70000, Max: 90000, CountPerBucket: countPerBucket(map[int]int64{len(latencyBuckets): 2})},
+			p:        0.5,
+			expected: 80000,
+		},
+	} {
+		if got := estimatePercentile(c.data, c.p); math.Abs(got-c.expected) > 1e-9 {
+			t.Errorf("%s: Got %v, expected %v", c.name, got, c.expected)
+		}
+	}
+}
+
+// namespaceResources returns the namespace resource gauges of the view recorded by the node, keyed by
+// namespace and resource.
+func namespaceResources(t *testing.T, v *view.View, node string) map[string]map[string]float64 {
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := make(map[string]map[string]float64)
+	for _, row := range rows {
+		tags := make(map[tag.Key]string)
+		for _, tg := range row.Tags {
+			tags[tg.Key] = tg.Value
+		}
+		if tags[keyNode] != node {
+			continue
+		}
+		if resources[tags[keyNamespace]] == nil {
+			resources[tags[keyNamespace]] = make(map[string]float64)
+		}
+		resources[tags[keyNamespace]][tags[keyResource]] = row.Data.(*view.LastValueData).Value
+	}
+	return resources
+}
+
+func TestRecordNamespaceResources(t *testing.T) {
+	if err := registerViews(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := mock.NewMockProviderWithConfig(mock.MockConfig{}, "vk-resources", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{nodeName: "vk-resources", provider: p}
+	ctx := context.Background()
+
+	makePod := func(namespace, name, cpu string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:      "c",
+				Image:     "nginx",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+			}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	running := makePod("a", "running", "500m", corev1.PodRunning)
+	if err := p.CreatePod(ctx, running); err != nil {
+		t.Fatal(err)
+	}
+	pods := []*corev1.Pod{
+		running,
+		makePod("a", "pending", "1", corev1.PodPending),
+		makePod("b", "succeeded", "2", corev1.PodSucceeded),
+	}
+
+	s.recordNamespaceResources(ctx, pods)
+	requested := namespaceResources(t, NamespaceRequestedView, s.nodeName)
+	if cpu := requested["a"]["cpu"]; cpu != 1.5 || len(requested) != 1 {
+		t.Errorf("Got requested resources %v, expected 1.5 cpu in namespace a only", requested)
+	}
+	used := namespaceResources(t, NamespaceUsedView, s.nodeName)
+	if cpu, n := used["a"]["cpu"], used["a"]["pods"]; cpu != 0.5 || n != 1 || len(used) != 1 {
+		t.Errorf("Got used resources %v, expected 0.5 cpu and 1 pod in namespace a only", used)
+	}
+
+	// The gauges of the namespaces without pods are reset.
+	if err := p.DeletePod(ctx, running); err != nil {
+		t.Fatal(err)
+	}
+	s.recordNamespaceResources(ctx, nil)
+	for _, v := range resourceViews {
+		for namespace, resources := range namespaceResources(t, v, s.nodeName) {
+			for name, value := range resources {
+				if value != 0 {
+					t.Errorf("Got %s %v of %s in namespace %s, expected 0 once every pod is deleted", v.Name, value, name, namespace)
+				}
+			}
+		}
+	}
+}
+
+func TestRecordPodCompletion(t *testing.T) {
+	if err := registerViews(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// The counts are cumulative, so the pods are in a namespace of their own
+	// and only the counts recorded by this run are compared.
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "completion", Name: "foo"}}
+	running := &corev1.PodStatus{Phase: corev1.PodRunning}
+	started := metav1.Now()
+
+	views := map[*view.View]int64{
+		PodsSucceededView: 1,
+		PodsFailedView:    1,
+		PodsRejectedView:  1,
+		PodsPreemptedView: 1,
+		PodsEvictedView:   2,
+	}
+	before := make(map[*view.View]int64, len(views))
+	for v := range views {
+		before[v] = namespaceCount(t, v, pod.Namespace)
+	}
+
+	for _, status := range []corev1.PodStatus{
+		{Phase: corev1.PodSucceeded, StartTime: &started},
+		{Phase: corev1.PodFailed, StartTime: &started, Reason: "Error"},
+		{Phase: corev1.PodFailed, Reason: "OutOfcpu"},
+		{Phase: corev1.PodFailed, StartTime: &started, Reason: providers.PodReasonPreempting},
+		{Phase: corev1.PodFailed, StartTime: &started, Reason: providers.PodReasonEvicted},
+		{Phase: corev1.PodFailed, StartTime: &started, Reason: providers.PodReasonEvicted},
+	} {
+		recordPodCompletion(ctx, pod, running, &status)
+	}
+	// A pod already reported failed is not counted again.
+	failed := &corev1.PodStatus{Phase: corev1.PodFailed, StartTime: &started, Reason: providers.PodReasonEvicted}
+	recordPodCompletion(ctx, pod, failed, failed)
+
+	for v, expected := range views {
+		if count := namespaceCount(t, v, pod.Namespace) - before[v]; count != expected {
+			t.Errorf("Got %s %d, expected %d", v.Name, count, expected)
+		}
+	}
+}
+
+// namespaceCount sums the counts of v recorded for namespace.
+func namespaceCount(t *testing.T, v *view.View, namespace string) int64 {
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	for _, row := range rows {
+		for _, tg := range row.Tags {
+			if tg.Key == keyNamespace && tg.Value == namespace {
+				count += row.Data.(*view.CountData).Value
+			}
+		}
+	}
+	return count
+}
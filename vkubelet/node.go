@@ -5,12 +5,19 @@ import (
 	"strings"
 
 	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"go.opencensus.io/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// Defaults of the system info of the node.
+	defaultNodeArchitecture = "amd64"
+	defaultKubeletVersion   = "v1.11.2"
+)
+
 // registerNode registers this virtual node with the Kubernetes API.
 func (s *Server) registerNode(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "registerNode")
@@ -37,11 +44,7 @@ func (s *Server) registerNode(ctx context.Context) error {
 			Taints: taints,
 		},
 		Status: corev1.NodeStatus{
-			NodeInfo: corev1.NodeSystemInfo{
-				OperatingSystem: s.provider.OperatingSystem(),
-				Architecture:    "amd64",
-				KubeletVersion:  "v1.11.2",
-			},
+			NodeInfo:        s.nodeInfo(ctx),
 			Capacity:        s.provider.Capacity(ctx),
 			Allocatable:     s.provider.Capacity(ctx),
 			Conditions:      s.provider.NodeConditions(ctx),
@@ -49,8 +52,22 @@ func (s *Server) registerNode(ctx context.Context) error {
 			DaemonEndpoints: *s.provider.NodeDaemonEndpoints(ctx),
 		},
 	}
+	if cp, ok := s.provider.(providers.PodCIDRProvider); ok {
+		node.Spec.PodCIDR = cp.PodCIDR(ctx)
+	}
 	addNodeAttributes(span, node)
-	if _, err := s.k8sClient.CoreV1().Nodes().Create(node); err != nil && !errors.IsAlreadyExists(err) {
+	created, err := s.k8sClient.CoreV1().Nodes().Create(node)
+	switch {
+	case err == nil:
+		s.setNode(created)
+	case errors.IsAlreadyExists(err):
+		// The existing node may carry labels and taints added since it was registered.
+		if existing, err := s.k8sClient.CoreV1().Nodes().Get(s.nodeName, metav1.GetOptions{}); err == nil {
+			s.setNode(existing)
+		} else {
+			s.setNode(node)
+		}
+	default:
 		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
 		return err
 	}
@@ -75,6 +92,9 @@ func (s *Server) updateNode(ctx context.Context) {
 	}
 	addNodeAttributes(span, n)
 	span.Annotate(nil, "Fetched node details from k8s")
+	if err == nil {
+		s.setNode(n.DeepCopy())
+	}
 
 	if errors.IsNotFound(err) {
 		if err = s.registerNode(ctx); err != nil {
@@ -94,6 +114,7 @@ func (s *Server) updateNode(ctx context.Context) {
 	n.Status.Allocatable = capacity
 
 	n.Status.Addresses = s.provider.NodeAddresses(ctx)
+	n.Status.NodeInfo = s.nodeInfo(ctx)
 
 	n, err = s.k8sClient.CoreV1().Nodes().UpdateStatus(n)
 	if err != nil {
@@ -103,6 +124,26 @@ func (s *Server) updateNode(ctx context.Context) {
 	}
 }
 
+// nodeInfo returns the system info of the node, as reported by the provider if it implements
+// providers.NodeInfoProvider.
+func (s *Server) nodeInfo(ctx context.Context) corev1.NodeSystemInfo {
+	var info corev1.NodeSystemInfo
+	if ip, ok := s.provider.(providers.NodeInfoProvider); ok {
+		info = ip.NodeInfo(ctx)
+	}
+
+	if info.OperatingSystem == "" {
+		info.OperatingSystem = s.provider.OperatingSystem()
+	}
+	if info.Architecture == "" {
+		info.Architecture = defaultNodeArchitecture
+	}
+	if info.KubeletVersion == "" {
+		info.KubeletVersion = defaultKubeletVersion
+	}
+	return info
+}
+
 type taintsStringer []corev1.Taint
 
 func (t taintsStringer) String() string {
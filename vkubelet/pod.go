@@ -3,10 +3,13 @@ package vkubelet
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	pkgerrors "github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/providers"
 	"go.opencensus.io/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -124,6 +127,10 @@ func (s *Server) syncPod(ctx context.Context, pod *corev1.Pod) {
 		if err := s.deletePod(ctx, pod); err != nil {
 			logger.WithError(err).Error("Failed to delete pod")
 		}
+	} else if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		// Terminal pods are never run again, even if the provider lost track of them after a restart.
+		span.Annotate(nil, "Skip terminal pod")
+		logger.Debugf("Skipping pod in phase %s", pod.Status.Phase)
 	} else {
 		span.Annotate(nil, "Create pod")
 		logger.Debugf("Creating pod")
@@ -133,30 +140,60 @@ func (s *Server) syncPod(ctx context.Context, pod *corev1.Pod) {
 	}
 }
 
-func (s *Server) createPod(ctx context.Context, pod *corev1.Pod) error {
+// createPod creates the pod in the provider. The cached pod is shared with the other goroutines, so the
+// environment variables are resolved on a copy, which is the one passed to the provider.
+// Pods which do not fit the node are rejected without reaching the provider.
+func (s *Server) createPod(ctx context.Context, cached *corev1.Pod) error {
 	ctx, span := trace.StartSpan(ctx, "createPod")
 	defer span.End()
-	addPodAttributes(span, pod)
+	addPodAttributes(span, cached)
+
+	if reason, message, rejected := s.admitPod(cached); rejected {
+		s.rejectPod(ctx, cached, reason, message)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeFailedPrecondition, Message: message})
+		return fmt.Errorf("pod rejected: %s", message)
+	}
+
+	pod := cached.DeepCopy()
+	if err := s.populateEnvironmentVariables(ctx, pod); err != nil {
+		if !waitingForConfig(pod) {
+			s.recordPodEvent(ctx, pod, corev1.EventTypeWarning, eventReasonFailed, "Error: %v", err)
+		}
+		// The pod stays pending until the Secrets and ConfigMaps it references exist.
+		// Its creation is retried by updatePodStatuses.
+		s.updatePodStatus(ctx, cached, configErrorPodStatus(pod, err))
 
-	if err := s.populateEnvironmentVariables(pod); err != nil {
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
 		return err
 	}
 
 	logger := log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace())
 
-	if origErr := s.provider.CreatePod(ctx, pod); origErr != nil {
+	recordPodBindingLatency(ctx, pod)
+	recordPodCreationLatency(ctx, pod)
+
+	start := time.Now()
+	origErr := s.provider.CreatePod(ctx, pod)
+	recordProviderLatency(ctx, "CreatePod", start)
+	if origErr != nil {
 		podPhase := corev1.PodPending
 		if pod.Spec.RestartPolicy == corev1.RestartPolicyNever {
 			podPhase = corev1.PodFailed
 		}
 
-		pod.ResourceVersion = "" // Blank out resource version to prevent object has been modified error
-		pod.Status.Phase = podPhase
-		pod.Status.Reason = podStatusReasonProviderFailed
-		pod.Status.Message = origErr.Error()
+		s.recordPodEvent(ctx, pod, corev1.EventTypeWarning, eventReasonProviderFailed, "Error creating pod in provider: %v", origErr)
+		if podPhase == corev1.PodFailed && pod.Status.Phase != corev1.PodFailed {
+			recordPodCount(ctx, pod.Namespace, mPodsRejected.M(1))
+		}
+
+		failed := pod.DeepCopy()
+		failed.ResourceVersion = "" // Blank out resource version to prevent object has been modified error
+		failed.Status.Phase = podPhase
+		failed.Status.Reason = podStatusReasonProviderFailed
+		failed.Status.Message = origErr.Error()
+		s.resourceManager.ReplacePod(cached, failed)
 
-		_, err := s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod)
+		_, err := s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(failed)
 		if err != nil {
 			logger.WithError(err).Warn("Failed to update pod status")
 		} else {
@@ -167,42 +204,109 @@ func (s *Server) createPod(ctx context.Context, pod *corev1.Pod) error {
 		return origErr
 	}
 	span.Annotate(nil, "Created pod in provider")
+	recordPodCreated(ctx, pod)
+
+	if waitingForConfig(pod) {
+		// Let the next status update report the status of the provider.
+		created := pod.DeepCopy()
+		created.Status.InitContainerStatuses = nil
+		created.Status.ContainerStatuses = nil
+		s.resourceManager.ReplacePod(cached, created)
+	}
 
 	logger.Info("Pod created")
 
 	return nil
 }
 
+// deletePod deletes the pod from the provider, then from Kubernetes.
+// Providers which terminate pods asynchronously keep them until their termination is complete, and push them
+// with their terminal status through the PodNotifier, which deletes them from Kubernetes then.
 func (s *Server) deletePod(ctx context.Context, pod *corev1.Pod) error {
 	ctx, span := trace.StartSpan(ctx, "deletePod")
 	defer span.End()
 	addPodAttributes(span, pod)
 
-	var delErr error
-	if delErr = s.provider.DeletePod(ctx, pod); delErr != nil && errors.IsNotFound(delErr) {
+	if s.startDeletion(pod) {
+		for _, c := range pod.Spec.Containers {
+			s.recordPodEvent(ctx, pod, corev1.EventTypeNormal, eventReasonKilling, "Killing container %s", c.Name)
+		}
+	}
+
+	start := time.Now()
+	delErr := s.provider.DeletePod(ctx, pod)
+	recordProviderLatency(ctx, "DeletePod", start)
+	if delErr != nil && errors.IsNotFound(delErr) {
+		s.endDeletion(pod)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: delErr.Error()})
 		return delErr
 	}
 	span.Annotate(nil, "Deleted pod from provider")
 
+	if delErr == nil && s.isTerminating(ctx, pod) {
+		span.Annotate(nil, "Pod is terminating in the provider")
+		log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Debug("Pod is terminating")
+		return nil
+	}
+
+	return s.deletePodFromKubernetes(ctx, pod)
+}
+
+// isTerminating reports whether the provider still holds the pod once DeletePod returned, which is the case
+// of PodNotifiers terminating pods asynchronously.
+func (s *Server) isTerminating(ctx context.Context, pod *corev1.Pod) bool {
+	if _, ok := s.provider.(providers.PodNotifier); !ok {
+		return false
+	}
+	p, err := s.provider.GetPod(ctx, pod.Namespace, pod.Name)
+	return err == nil && p != nil
+}
+
+// startDeletion marks the deletion of the pod started, and reports whether it was not already.
+func (s *Server) startDeletion(pod *corev1.Pod) bool {
+	key := pod.GetNamespace() + "/" + pod.GetName()
+
+	s.deletingMu.Lock()
+	defer s.deletingMu.Unlock()
+	if s.deleting[key] {
+		return false
+	}
+	s.deleting[key] = true
+	return true
+}
+
+// endDeletion forgets the deletion of the pod once it is gone.
+func (s *Server) endDeletion(pod *corev1.Pod) {
+	s.deletingMu.Lock()
+	delete(s.deleting, pod.GetNamespace()+"/"+pod.GetName())
+	s.deletingMu.Unlock()
+}
+
+// deletePodFromKubernetes deletes the pod from Kubernetes and from the internal state, once it is gone from the provider.
+func (s *Server) deletePodFromKubernetes(ctx context.Context, pod *corev1.Pod) error {
+	ctx, span := trace.StartSpan(ctx, "deletePodFromKubernetes")
+	defer span.End()
+	addPodAttributes(span, pod)
+
 	logger := log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace())
-	if !errors.IsNotFound(delErr) {
-		var grace int64
-		if err := s.k8sClient.CoreV1().Pods(pod.GetNamespace()).Delete(pod.GetName(), &metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil && errors.IsNotFound(err) {
-			if errors.IsNotFound(err) {
-				span.Annotate(nil, "Pod does not exist in k8s, nothing to delete")
-				return nil
-			}
 
-			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
-			return fmt.Errorf("Failed to delete kubernetes pod: %s", err)
+	var grace int64
+	if err := s.k8sClient.CoreV1().Pods(pod.GetNamespace()).Delete(pod.GetName(), &metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil && errors.IsNotFound(err) {
+		if errors.IsNotFound(err) {
+			s.endDeletion(pod)
+			span.Annotate(nil, "Pod does not exist in k8s, nothing to delete")
+			return nil
 		}
-		span.Annotate(nil, "Deleted pod from k8s")
 
-		s.resourceManager.DeletePod(pod)
-		span.Annotate(nil, "Deleted pod from internal state")
-		logger.Info("Pod deleted")
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+		return fmt.Errorf("Failed to delete kubernetes pod: %s", err)
 	}
+	span.Annotate(nil, "Deleted pod from k8s")
+
+	s.resourceManager.DeletePod(pod)
+	s.endDeletion(pod)
+	span.Annotate(nil, "Deleted pod from internal state")
+	logger.Info("Pod deleted")
 
 	return nil
 }
@@ -213,8 +317,10 @@ func (s *Server) updatePodStatuses(ctx context.Context) {
 	defer span.End()
 
 	// Update all the pods with the provider status.
-	pods := s.resourceManager.GetPods()
+	pods := s.getPods()
 	span.AddAttributes(trace.Int64Attribute("nPods", int64(len(pods))))
+	defer s.recordPodGauges(ctx, pods)
+	defer s.recordNamespaceResources(ctx, pods)
 
 	for _, pod := range pods {
 		if pod.Status.Phase == corev1.PodSucceeded ||
@@ -223,20 +329,127 @@ func (s *Server) updatePodStatuses(ctx context.Context) {
 			continue
 		}
 
+		// Pods waiting for their Secrets and ConfigMaps have not been created in the provider yet.
+		if waitingForConfig(pod) {
+			if err := s.createPod(ctx, pod); err != nil {
+				log.G(ctx).WithError(err).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Debug("Pod is still waiting for its configuration")
+			}
+			continue
+		}
+
+		start := time.Now()
 		status, err := s.provider.GetPodStatus(ctx, pod.Namespace, pod.Name)
+		recordProviderLatency(ctx, "GetPodStatus", start)
 		if err != nil {
-			log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Error("Error retrieving pod status")
-			return
+			// The statuses of the other pods are still synced.
+			log.G(ctx).WithError(err).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Error("Error retrieving pod status")
+			continue
 		}
 
 		// Update the pod's status
 		if status != nil {
-			pod.Status = *status
-			s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(pod)
+			s.updatePodStatus(ctx, pod, status)
 		}
 	}
 }
 
+// updatePodStatus updates the status of the pod within Kubernetes.
+// Nothing is written if the status did not change, to avoid needless requests to the API server.
+// The cached pod is shared with the other goroutines, so it is replaced with an updated copy. If the cached pod
+// changed meanwhile, e.g. because another goroutine updated its status first, nothing is written either:
+// the next sync of the pod statuses reports the current status.
+func (s *Server) updatePodStatus(ctx context.Context, pod *corev1.Pod, status *corev1.PodStatus) {
+	if reflect.DeepEqual(&pod.Status, status) {
+		return
+	}
+
+	updated := pod.DeepCopy()
+	updated.Status = *status
+	if !s.resourceManager.ReplacePod(pod, updated) {
+		log.G(ctx).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Debug("Pod changed while updating its status")
+		return
+	}
+
+	s.recordContainerEvents(ctx, pod, &pod.Status, status)
+	s.recordRejectionEvent(ctx, pod, &pod.Status, status)
+	recordPodStartupLatency(ctx, pod, &pod.Status, status)
+	recordPodCompletion(ctx, pod, &pod.Status, status)
+	if _, err := s.k8sClient.CoreV1().Pods(pod.Namespace).UpdateStatus(updated); err != nil {
+		log.G(ctx).WithError(err).WithField("pod", pod.GetName()).WithField("namespace", pod.GetNamespace()).Warn("Failed to update pod status")
+	}
+}
+
+// watchPodStatusNotifications updates the status of the pods notified by the provider within Kubernetes,
+// until the notification channel is closed, the context is cancelled or Stop is called.
+// Pods being deleted are deleted from Kubernetes once the provider notifies them terminated.
+func (s *Server) watchPodStatusNotifications(ctx context.Context, notifications <-chan *corev1.Pod) {
+	defer s.syncLoops.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stop:
+			return
+		case notified, ok := <-notifications:
+			if !ok {
+				return
+			}
+
+			if notified.DeletionTimestamp != nil && (notified.Status.Phase == corev1.PodSucceeded || notified.Status.Phase == corev1.PodFailed) {
+				if err := s.deletePodFromKubernetes(ctx, notified); err != nil {
+					log.G(ctx).WithError(err).WithField("pod", notified.GetName()).WithField("namespace", notified.GetNamespace()).Error("Failed to delete terminated pod")
+				}
+				continue
+			}
+
+			pod := s.resourceManager.GetPod(notified.Namespace, notified.Name)
+			if pod == nil ||
+				pod.Status.Phase == corev1.PodSucceeded ||
+				pod.Status.Phase == corev1.PodFailed ||
+				pod.Status.Reason == podStatusReasonProviderFailed {
+				continue
+			}
+
+			ctx, span := trace.StartSpan(ctx, "podStatusNotification")
+			addPodAttributes(span, pod)
+			s.updatePodStatus(ctx, pod, &notified.Status)
+			span.End()
+		}
+	}
+}
+
+// getPods returns the pods of the node known to the resource manager, which may be shared by the servers
+// of several nodes.
+func (s *Server) getPods() []*corev1.Pod {
+	pods := s.resourceManager.GetPods()
+	nodePods := pods[:0]
+	for _, pod := range pods {
+		if pod.Spec.NodeName == s.nodeName {
+			nodePods = append(nodePods, pod)
+		}
+	}
+	return nodePods
+}
+
+// listNodePods lists the pods of the node. The pods bound to other nodes are filtered out in addition to
+// the field selector of opts, as the in-process fake client used to run standalone ignores field selectors.
+func (s *Server) listNodePods(opts metav1.ListOptions) (*corev1.PodList, error) {
+	pods, err := s.k8sClient.CoreV1().Pods(s.namespace).List(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	items := pods.Items[:0]
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == s.nodeName {
+			items = append(items, pod)
+		}
+	}
+	pods.Items = items
+	return pods, nil
+}
+
 // watchForPodEvent waits for pod changes from kubernetes and updates the details accordingly in the local state.
 // This returns after a single pod event.
 func (s *Server) watchForPodEvent(ctx context.Context) error {
@@ -244,7 +457,7 @@ func (s *Server) watchForPodEvent(ctx context.Context) error {
 		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", s.nodeName).String(),
 	}
 
-	pods, err := s.k8sClient.CoreV1().Pods(s.namespace).List(opts)
+	pods, err := s.listNodePods(opts)
 	if err != nil {
 		return pkgerrors.Wrap(err, "error getting pod list")
 	}
@@ -264,7 +477,7 @@ func (s *Server) watchForPodEvent(ctx context.Context) error {
 					opts.ResourceVersion = controller.LastSyncResourceVersion()
 				}
 
-				return s.k8sClient.Core().Pods(s.namespace).List(opts)
+				return s.listNodePods(opts)
 			},
 
 			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
@@ -272,7 +485,14 @@ func (s *Server) watchForPodEvent(ctx context.Context) error {
 					opts.ResourceVersion = controller.LastSyncResourceVersion()
 				}
 
-				return s.k8sClient.Core().Pods(s.namespace).Watch(opts)
+				w, err := s.k8sClient.Core().Pods(s.namespace).Watch(opts)
+				if err != nil {
+					return nil, err
+				}
+				return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+					pod, ok := e.Object.(*corev1.Pod)
+					return e, !ok || pod.Spec.NodeName == s.nodeName
+				}), nil
 			},
 		},
 
@@ -293,12 +513,31 @@ func (s *Server) watchForPodEvent(ctx context.Context) error {
 		},
 	)
 
+	var workers sync.WaitGroup
+	workers.Add(s.podSyncWorkers)
 	for i := 0; i < s.podSyncWorkers; i++ {
-		go s.startPodSynchronizer(ctx, i)
+		go func(id int) {
+			defer workers.Done()
+			s.startPodSynchronizer(ctx, id)
+		}(i)
 	}
 
+	// The controller runs until Stop is called or ctx is done. Its event handlers are the only senders
+	// on podCh, and they are done once Run returns, so podCh can be closed then.
+	stopCh := make(chan struct{})
+	go func() {
+		defer close(stopCh)
+		select {
+		case <-s.stop:
+		case <-ctx.Done():
+		}
+	}()
+
 	log.G(ctx).Info("Start to run pod cache controller.")
-	controller.Run(ctx.Done())
+	controller.Run(stopCh)
+
+	close(s.podCh)
+	workers.Wait()
 
 	return ctx.Err()
 }
@@ -2,7 +2,10 @@ package vkubelet
 
 import (
 	"context"
+	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	pkgerrors "github.com/pkg/errors"
@@ -16,24 +19,65 @@ import (
 
 const (
 	podStatusReasonProviderFailed = "ProviderFailed"
+
+	// podStatusNotificationBuffer is the number of pod status notifications from the provider
+	// which can be queued before the provider is blocked.
+	podStatusNotificationBuffer = 1024
+
+	// defaultNodeStatusUpdateInterval is the default interval between updates of the node status.
+	defaultNodeStatusUpdateInterval = 5 * time.Second
+
+	// podStatusUpdateInterval is the interval between syncs of the pod statuses with the provider.
+	podStatusUpdateInterval = 5 * time.Second
 )
 
 // Server masquarades itself as a kubelet and allows for the virtual node to be backed by non-vm/node providers.
 type Server struct {
 	nodeName        string
 	namespace       string
-	k8sClient       *kubernetes.Clientset
+	k8sClient       kubernetes.Interface
 	taint           *corev1.Taint
 	provider        providers.Provider
 	resourceManager *manager.ResourceManager
 	podSyncWorkers  int
 	podCh           chan *podNotification
+
+	nodeLeaseDurationSeconds int32
+	nodeStatusUpdateInterval time.Duration
+
+	// ready is set to 1 once the node is registered.
+	ready int32
+
+	// gaugeNamespaces are the namespaces the pod gauges were last recorded for, and requestedGauges and
+	// usedGauges the namespace resource gauges last recorded.
+	gaugeNamespaces map[string]bool
+	requestedGauges map[resourceGauge]bool
+	usedGauges      map[resourceGauge]bool
+
+	// node is the last known node object of the virtual node, whose labels and taints pods are admitted against.
+	nodeMu sync.Mutex
+	node   *corev1.Node
+
+	// deleting holds the namespace/name keys of the pods whose deletion started, whose containers
+	// are reported killed only once however many times the deletion is retried.
+	deletingMu sync.Mutex
+	deleting   map[string]bool
+
+	// stop is closed by Stop to stop the sync loops, the node lease renewal and the pod status
+	// notification watcher, which are all tracked by syncLoops.
+	stop      chan struct{}
+	syncLoops sync.WaitGroup
+
+	// podWatch tracks the pod informer and the pod synchronizers started by Run, which also stop
+	// once stop is closed. stopMu orders the start of Run with Stop.
+	stopMu   sync.Mutex
+	podWatch sync.WaitGroup
 }
 
 // Config is used to configure a new server.
 type Config struct {
 	APIConfig       APIConfig
-	Client          *kubernetes.Clientset
+	Client          kubernetes.Interface
 	MetricsAddr     string
 	Namespace       string
 	NodeName        string
@@ -41,6 +85,18 @@ type Config struct {
 	ResourceManager *manager.ResourceManager
 	Taint           *corev1.Taint
 	PodSyncWorkers  int
+
+	// NodeLeaseDurationSeconds is the duration of the node lease, which is renewed every quarter of it.
+	// The node lease is not used if it is zero.
+	NodeLeaseDurationSeconds int32
+
+	// NodeStatusUpdateInterval is the interval between updates of the node status, which carry the heartbeats
+	// of the node conditions. It defaults to 5 seconds.
+	NodeStatusUpdateInterval time.Duration
+
+	// EnableProfiling serves the runtime profiles of the process on /debug/pprof/ of the metrics server.
+	// It is off by default, as the profiles expose the internals of the process to anyone reaching the server.
+	EnableProfiling bool
 }
 
 // APIConfig is used to configure the API server of the virtual kubelet.
@@ -66,10 +122,22 @@ func New(ctx context.Context, cfg Config) (s *Server, retErr error) {
 		provider:        cfg.Provider,
 		podSyncWorkers:  cfg.PodSyncWorkers,
 		podCh:           make(chan *podNotification, cfg.PodSyncWorkers),
+		stop:            make(chan struct{}),
+		deleting:        make(map[string]bool),
+
+		nodeLeaseDurationSeconds: cfg.NodeLeaseDurationSeconds,
+		nodeStatusUpdateInterval: cfg.NodeStatusUpdateInterval,
+	}
+	if s.nodeStatusUpdateInterval <= 0 {
+		s.nodeStatusUpdateInterval = defaultNodeStatusUpdateInterval
 	}
 
 	ctx = log.WithLogger(ctx, log.G(ctx))
 
+	if err := registerViews(); err != nil {
+		return nil, pkgerrors.Wrap(err, "error registering metrics views")
+	}
+
 	apiL, err := net.Listen("tcp", cfg.APIConfig.Addr)
 	if err != nil {
 		return nil, pkgerrors.Wrap(err, "error setting up API listener")
@@ -92,7 +160,7 @@ func New(ctx context.Context, cfg Config) (s *Server, retErr error) {
 				metricsL.Close()
 			}
 		}()
-		go MetricsServerStart(cfg.Provider, metricsL)
+		go serveMetrics(metricsL, metricsHandler(cfg.Provider, s.isReady, cfg.EnableProfiling))
 	} else {
 		log.G(ctx).Info("Skipping metrics server startup since no address was provided")
 	}
@@ -100,24 +168,77 @@ func New(ctx context.Context, cfg Config) (s *Server, retErr error) {
 	if err := s.registerNode(ctx); err != nil {
 		return s, err
 	}
+	atomic.StoreInt32(&s.ready, 1)
+
+	if pn, ok := s.provider.(providers.PodNotifier); ok {
+		notifications := make(chan *corev1.Pod, podStatusNotificationBuffer)
+		pn.NotifyPods(ctx, func(pod *corev1.Pod) {
+			// Once the watcher is gone, the notification is dropped rather than blocking the provider.
+			select {
+			case notifications <- pod:
+			case <-s.stop:
+			case <-ctx.Done():
+			}
+		})
+		s.syncLoops.Add(1)
+		go s.watchPodStatusNotifications(ctx, notifications)
+	}
+
+	if s.nodeLeaseDurationSeconds > 0 {
+		s.syncLoops.Add(1)
+		go s.runNodeLease(ctx)
+	}
 
-	tick := time.Tick(5 * time.Second)
+	s.syncLoops.Add(2)
+	go s.runSyncLoop(ctx, "syncNodeStatus", s.nodeStatusUpdateInterval, s.updateNode)
+	go s.runSyncLoop(ctx, "syncActualState", podStatusUpdateInterval, s.updatePodStatuses)
 
-	go func() {
-		for range tick {
-			ctx, span := trace.StartSpan(ctx, "syncActualState")
-			s.updateNode(ctx)
-			s.updatePodStatuses(ctx)
+	return s, nil
+}
+
+// runSyncLoop calls fn every interval until ctx is done or Stop is called.
+// When stopped by Stop, fn is called one last time so that pending updates are flushed.
+func (s *Server) runSyncLoop(ctx context.Context, name string, interval time.Duration, fn func(context.Context)) {
+	defer s.syncLoops.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			ctx, span := trace.StartSpan(ctx, name)
+			span.Annotate(nil, "Flushing on stop")
+			fn(ctx)
 			span.End()
+			return
+		case <-ctx.Done():
+			return
 		}
-	}()
 
-	return s, nil
+		start := time.Now()
+		ctx, span := trace.StartSpan(ctx, name)
+		fn(ctx)
+		span.End()
+		recordSyncLoopDuration(ctx, name, start)
+	}
 }
 
 // Run starts the server, registers it with Kubernetes and begins watching/reconciling the cluster.
 // Run will block until Stop is called or a SIGINT or SIGTERM signal is received.
 func (s *Server) Run(ctx context.Context) error {
+	s.stopMu.Lock()
+	select {
+	case <-s.stop:
+		s.stopMu.Unlock()
+		return nil
+	default:
+	}
+	s.podWatch.Add(1)
+	s.stopMu.Unlock()
+	defer s.podWatch.Done()
+
 	if err := s.watchForPodEvent(ctx); err != nil {
 		if pkgerrors.Cause(err) == context.Canceled {
 			return err
@@ -130,8 +251,27 @@ func (s *Server) Run(ctx context.Context) error {
 
 // Stop shutsdown the server.
 // It does not shutdown pods assigned to the virtual node.
+// The pod informer is stopped first, and the pods being synced are synced to completion. The node and pod
+// statuses are then updated one last time, and the provider is closed last if it implements io.Closer.
+// The resource manager is left running, as it may be shared with other servers.
 func (s *Server) Stop() {
-	close(s.podCh)
+	s.stopMu.Lock()
+	close(s.stop)
+	s.stopMu.Unlock()
+
+	s.podWatch.Wait()
+	s.syncLoops.Wait()
+
+	if c, ok := s.provider.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.L.WithError(err).Error("Error closing provider")
+		}
+	}
+}
+
+// isReady reports whether the node is registered.
+func (s *Server) isReady() bool {
+	return atomic.LoadInt32(&s.ready) == 1
 }
 
 // reconcile is the main reconciliation loop that compares differences between Kubernetes and
@@ -144,7 +284,9 @@ func (s *Server) reconcile(ctx context.Context) {
 	logger.Debug("Start reconcile")
 	defer logger.Debug("End reconcile")
 
+	start := time.Now()
 	providerPods, err := s.provider.GetPods(ctx)
+	recordProviderLatency(ctx, "GetPods", start)
 	if err != nil {
 		logger.WithError(err).Error("Error getting pod list from provider")
 		return
@@ -162,7 +304,7 @@ func (s *Server) reconcile(ctx context.Context) {
 	var failedDeleteCount int64
 	for _, pod := range deletePods {
 		logger := logger.WithField("pod", pod.Name)
-		logger.Debug("Deleting pod '%s'\n", pod.Name)
+		logger.Debugf("Deleting pod '%s'", pod.Name)
 		if err := s.deletePod(ctx, pod); err != nil {
 			logger.WithError(err).Error("Error deleting pod")
 			failedDeleteCount++
@@ -177,7 +319,7 @@ func (s *Server) reconcile(ctx context.Context) {
 		"Cleaned up stale provider pods",
 	)
 
-	pods := s.resourceManager.GetPods()
+	pods := s.getPods()
 
 	var createPods []*corev1.Pod
 	cleanupPods := deletePods[:0]
@@ -0,0 +1,188 @@
+package vkubelet
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// closeCheckingProvider fails the test when pods are created or deleted once the provider is closed.
+type closeCheckingProvider struct {
+	*mock.MockProvider
+	t      *testing.T
+	closed int32
+}
+
+func (p *closeCheckingProvider) CreatePod(ctx context.Context, pod *corev1.Pod) error {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		p.t.Errorf("Pod %s created after the provider was closed", pod.Name)
+	}
+	return p.MockProvider.CreatePod(ctx, pod)
+}
+
+func (p *closeCheckingProvider) DeletePod(ctx context.Context, pod *corev1.Pod) error {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		p.t.Errorf("Pod %s deleted after the provider was closed", pod.Name)
+	}
+	return p.MockProvider.DeletePod(ctx, pod)
+}
+
+func (p *closeCheckingProvider) Close() error {
+	atomic.StoreInt32(&p.closed, 1)
+	return p.MockProvider.Close()
+}
+
+func TestStopWhileWatchingPods(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rm, err := manager.NewResourceManager(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+
+	mp, err := mock.NewMockProviderWithConfig(mock.MockConfig{}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &closeCheckingProvider{MockProvider: mp, t: t}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(ctx, Config{
+		Client:          client,
+		NodeName:        "vk",
+		Provider:        provider,
+		ResourceManager: rm,
+		APIConfig:       APIConfig{Addr: "127.0.0.1:0"},
+		PodSyncWorkers:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+	}()
+
+	// Pods keep being added and deleted while the server stops.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: corev1.NamespaceDefault},
+				Spec:       corev1.PodSpec{NodeName: "vk", Containers: []corev1.Container{{Name: "c", Image: "busybox"}}},
+			}
+			client.CoreV1().Pods(pod.Namespace).Create(pod)
+			client.CoreV1().Pods(pod.Namespace).Delete(pod.Name, nil)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("Unexpected error from Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+	<-done
+}
+
+func TestGetPodsOfSharedResourceManager(t *testing.T) {
+	rm, err := manager.NewResourceManager(fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+
+	pods := &corev1.PodList{}
+	for i, node := range []string{"vk-0", "vk-1", "vk-1"} {
+		pods.Items = append(pods.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: corev1.NamespaceDefault},
+			Spec:       corev1.PodSpec{NodeName: node},
+		})
+	}
+	rm.SetPods(pods)
+
+	for node, expected := range map[string]int{"vk-0": 1, "vk-1": 2, "vk-2": 0} {
+		s := &Server{nodeName: node, resourceManager: rm}
+		got := s.getPods()
+		if len(got) != expected {
+			t.Errorf("Got %d pods of node %s, expected %d", len(got), node, expected)
+		}
+		for _, pod := range got {
+			if pod.Spec.NodeName != node {
+				t.Errorf("Got pod %s of node %s, expected only pods of node %s", pod.Name, pod.Spec.NodeName, node)
+			}
+		}
+	}
+}
+
+// statusFailingProvider fails to get the status of the pods named failing.
+type statusFailingProvider struct {
+	*mock.MockProvider
+	failing string
+}
+
+func (p *statusFailingProvider) GetPodStatus(ctx context.Context, namespace, name string) (*corev1.PodStatus, error) {
+	if name == p.failing {
+		return nil, fmt.Errorf("status of %s unavailable", name)
+	}
+	return p.MockProvider.GetPodStatus(ctx, namespace, name)
+}
+
+func TestUpdatePodStatusesAfterError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rm, err := manager.NewResourceManager(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+
+	mp, err := mock.NewMockProviderWithConfig(mock.MockConfig{}, "vk", "Linux", "10.0.0.1", 10250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{k8sClient: client, nodeName: "vk", provider: &statusFailingProvider{MockProvider: mp, failing: "a"}, resourceManager: rm}
+	ctx := context.Background()
+
+	pods := &corev1.PodList{}
+	for _, name := range []string{"a", "b", "c"} {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: corev1.NamespaceDefault},
+			Spec:       corev1.PodSpec{NodeName: "vk", Containers: []corev1.Container{{Name: "c", Image: "busybox"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		}
+		if _, err := client.CoreV1().Pods(pod.Namespace).Create(&pod); err != nil {
+			t.Fatal(err)
+		}
+		if err := mp.CreatePod(ctx, &pod); err != nil {
+			t.Fatal(err)
+		}
+		pods.Items = append(pods.Items, pod)
+	}
+	rm.SetPods(pods)
+
+	s.updatePodStatuses(ctx)
+
+	for name, expected := range map[string]corev1.PodPhase{"a": corev1.PodPending, "b": corev1.PodRunning, "c": corev1.PodRunning} {
+		if phase := rm.GetPod(corev1.NamespaceDefault, name).Status.Phase; phase != expected {
+			t.Errorf("Got phase %s for %s, expected %s", phase, name, expected)
+		}
+	}
+}